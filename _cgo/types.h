@@ -24,6 +24,8 @@
 typedef struct exif_value {
 	char *name;
 	char *value;
+	const char *description;
+	ExifIfd ifd;
 	ExifEntry *rawValue;
 	struct exif_value* prev;
 } exif_value_t;
@@ -32,4 +34,5 @@ typedef struct exif_stack {
 	struct exif_value* head;
 } exif_stack_t;
 
-ExifRational exif_get_rational_offset (const unsigned char *buf, ExifByteOrder order, int offset);
\ No newline at end of file
+ExifRational exif_get_rational_offset (const unsigned char *buf, ExifByteOrder order, int offset);
+ExifSRational exif_get_srational_offset (const unsigned char *buf, ExifByteOrder order, int offset);
\ No newline at end of file
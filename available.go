@@ -0,0 +1,22 @@
+package exif
+
+// libexifAvailable reports whether libexif is usable. A program linked against
+// it always is, see Available; it is a variable so that tests can stand in for
+// a missing library.
+var libexifAvailable = func() bool {
+	return true
+}
+
+// Available reports whether libexif is usable. When it is not, parsing,
+// HasExif and WriteTo return ErrLibexifUnavailable, and HasExifBytes returns
+// false. StripExif and SetOrientation only edit JPEG segments and never need
+// libexif.
+//
+// A program dynamically linked against a libexif shared library that is
+// missing at runtime fails before any of its code runs, so Available cannot
+// report that case and is true in any program that gets to call it. Build
+// with the libexif_static tag on Linux to link libexif statically instead, so
+// the binary does not need the shared library at all.
+func Available() bool {
+	return libexifAvailable()
+}
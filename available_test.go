@@ -0,0 +1,40 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestAvailable(t *testing.T) {
+	assert.True(t, Available())
+
+	available := libexifAvailable
+	defer func() {
+		libexifAvailable = available
+	}()
+	libexifAvailable = func() bool {
+		return false
+	}
+	assert.False(t, Available())
+
+	_, err := Read("_examples/resources/test.jpg")
+	assert.Equal(t, ErrLibexifUnavailable, err)
+
+	tiff := buildTIFF([]testEntry{asciiEntry(TagMake, "Maker")}, nil, nil)
+	_, err = ReadBytes(tiff)
+	assert.Equal(t, ErrLibexifUnavailable, err)
+
+	_, err = HasExif("_examples/resources/test.jpg")
+	assert.Equal(t, ErrLibexifUnavailable, err)
+	assert.False(t, HasExifBytes(tiff))
+
+	_, err = New().WriteTo(io.Discard)
+	assert.Equal(t, ErrLibexifUnavailable, err)
+
+	// Editing JPEG segments does not need libexif.
+	_, err = SetOrientation(buildJPEG(t, tiff), OrientationRightTop)
+	assert.NoError(t, err)
+	_, err = StripExif(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,48 @@
+package exif
+
+import (
+	"runtime"
+	"sync"
+)
+
+// ReadDir reads the EXIF data of the given files in parallel, using at most
+// concurrency goroutines (runtime.GOMAXPROCS(0) if concurrency is less than
+// 1). It returns the parsed data and the errors keyed by path; every path ends
+// up in exactly one of the two maps.
+func ReadDir(paths []string, concurrency int) (map[string]*Data, map[string]error) {
+	if concurrency < 1 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	results := make(map[string]*Data, len(paths))
+	errs := make(map[string]error)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	jobs := make(chan string)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				data, err := Read(path)
+				mu.Lock()
+				if err != nil {
+					errs[path] = err
+				} else {
+					results[path] = data
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, path := range paths {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, errs
+}
@@ -0,0 +1,22 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReadDir(t *testing.T) {
+	paths := []string{
+		"_examples/resources/test.jpg",
+		"_examples/resources/testlocation.jpg",
+		"_examples/resources/missing.jpg",
+	}
+
+	results, errs := ReadDir(paths, 2)
+	assert.Equal(t, 2, len(results))
+	assert.Equal(t, 1, len(errs))
+
+	assert.Equal(t, "FUJIFILM", results["_examples/resources/test.jpg"].Make())
+	assert.Equal(t, "LGE", results["_examples/resources/testlocation.jpg"].Make())
+	assert.Error(t, errs["_examples/resources/missing.jpg"])
+}
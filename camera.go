@@ -0,0 +1,160 @@
+package exif
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Make returns the manufacturer of the recording equipment, or an empty
+// string if it is not present.
+func (d *Data) Make() string {
+	return d.textValue(TagMake)
+}
+
+// Model returns the model name of the recording equipment, or an empty string
+// if it is not present.
+func (d *Data) Model() string {
+	return d.textValue(TagModel)
+}
+
+// LensModel returns the model name of the lens, or an empty string if it is
+// not present.
+func (d *Data) LensModel() string {
+	return d.textValue(TagLensModel)
+}
+
+// ExposureTime returns the exposure time in its conventional notation, like
+// "1/250 s" for fractions of a second or "2.5 s" for longer exposures.
+func (d *Data) ExposureTime() (string, bool) {
+	tag, ok := d.Tags[TagExposureTime].(FloatTag)
+	if !ok || tag.FloatValue() <= 0 {
+		return "", false
+	}
+
+	seconds := tag.FloatValue()
+	if seconds >= 1 {
+		return strconv.FormatFloat(seconds, 'f', -1, 64) + " s", true
+	}
+	if tag.Numerator() == 1 {
+		return fmt.Sprintf("1/%d s", tag.Denominator()), true
+	}
+	if inverse := 1 / seconds; math.Abs(inverse-math.Round(inverse)) < 1e-9 {
+		return fmt.Sprintf("1/%d s", int(math.Round(inverse))), true
+	}
+	return strconv.FormatFloat(seconds, 'f', -1, 64) + " s", true
+}
+
+// ShutterSpeed returns the shutter speed in seconds, converted from the APEX
+// ShutterSpeedValue tag.
+func (d *Data) ShutterSpeed() (float64, bool) {
+	tag, ok := d.Tags[TagShutterSpeedValue].(FloatTag)
+	if !ok {
+		return 0, false
+	}
+	return ApexToShutterSeconds(tag.FloatValue()), true
+}
+
+// FNumber returns the f-number of the exposure. It falls back to the APEX
+// ApertureValue tag when FNumber is not present.
+func (d *Data) FNumber() (float64, bool) {
+	if tag, ok := d.Tags[TagFNumber].(FloatTag); ok {
+		return tag.FloatValue(), true
+	}
+	if tag, ok := d.Tags[TagApertureValue].(FloatTag); ok {
+		return ApexToFNumber(tag.FloatValue()), true
+	}
+	return 0, false
+}
+
+// ApexToShutterSeconds converts an APEX time value (Tv), as stored in
+// ShutterSpeedValue, to an exposure time in seconds: 2^-Tv.
+func ApexToShutterSeconds(v float64) float64 {
+	return math.Pow(2, -v)
+}
+
+// ApexToFNumber converts an APEX aperture value (Av), as stored in
+// ApertureValue and MaxApertureValue, to an f-number: sqrt(2)^Av.
+func ApexToFNumber(v float64) float64 {
+	return math.Pow(math.Sqrt2, v)
+}
+
+// FocalLength returns the focal length of the lens in millimeters.
+func (d *Data) FocalLength() (float64, bool) {
+	tag, ok := d.Tags[TagFocalLength].(FloatTag)
+	if !ok {
+		return 0, false
+	}
+	return tag.FloatValue(), true
+}
+
+// FocalLength35mm returns the equivalent focal length for 35mm film in
+// millimeters. A value of 0 means unknown and is reported as absent.
+func (d *Data) FocalLength35mm() (int, bool) {
+	tag, ok := d.Tags[TagFocalLengthIn35mmFilm].(IntegerTag)
+	if !ok || tag.IntValue() == 0 {
+		return 0, false
+	}
+	return tag.IntValue(), true
+}
+
+// ISO returns the ISO sensitivity of the exposure, checking
+// ISOSpeedRatings (PhotographicSensitivity), RecommendedExposureIndex and
+// ISOSpeed in that order. ISOSpeedRatings saturates at 65535, in which case a
+// value from the other tags is preferred.
+func (d *Data) ISO() (int, bool) {
+	var saturated bool
+	for _, id := range []int{TagISOSpeedRatings, TagRecommendedExposureIndex, TagISOSpeed} {
+		tag, ok := d.Tags[id].(IntegerTag)
+		if !ok || tag.IntValue() == 0 {
+			continue
+		}
+		if id == TagISOSpeedRatings && tag.IntValue() == 0xFFFF {
+			saturated = true
+			continue
+		}
+		return tag.IntValue(), true
+	}
+	if saturated {
+		return 0xFFFF, true
+	}
+	return 0, false
+}
+
+const FlashModeUnknown = 0
+const FlashModeCompulsoryFiring = 1
+const FlashModeCompulsorySuppression = 2
+const FlashModeAuto = 3
+
+// FlashInfo is the decoded Flash bitfield.
+type FlashInfo struct {
+	// Fired reports whether the flash fired.
+	Fired bool
+	// Return is the status of returned light, 0 when no strobe return
+	// detection is available, 2 when return light was not detected and 3
+	// when it was.
+	Return int
+	// Mode is one of the FlashMode constants.
+	Mode int
+	// FunctionPresent reports whether the camera has a flash function.
+	FunctionPresent bool
+	// RedEyeReduction reports whether red-eye reduction was used.
+	RedEyeReduction bool
+}
+
+// Flash returns the decoded Flash tag. The raw bitfield remains available
+// through the IntegerTag stored under TagFlash.
+func (d *Data) Flash() (FlashInfo, bool) {
+	tag, ok := d.Tags[TagFlash].(IntegerTag)
+	if !ok {
+		return FlashInfo{}, false
+	}
+	flash := tag.IntValue()
+	return FlashInfo{
+		Fired:           flash&0x01 != 0,
+		Return:          (flash >> 1) & 0x03,
+		Mode:            (flash >> 3) & 0x03,
+		FunctionPresent: flash&0x20 == 0,
+		RedEyeReduction: flash&0x40 != 0,
+	}, true
+}
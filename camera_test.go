@@ -0,0 +1,151 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestMakeModel(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	assert.Equal(t, "FUJIFILM", exif.Make())
+	assert.Equal(t, "MX-1700ZOOM", exif.Model())
+	assert.Equal(t, "", exif.LensModel())
+}
+
+func TestExposureTime(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+
+	exposure, ok := exif.ExposureTime()
+	assert.True(t, ok)
+	assert.Equal(t, "1/119 s", exposure)
+
+	tiff := buildTIFF(nil, []testEntry{rationalEntry(TagExposureTime, exifFormatFloat, 10, 2500)}, nil)
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	exposure, ok = exif.ExposureTime()
+	assert.True(t, ok)
+	assert.Equal(t, "1/250 s", exposure)
+
+	tiff = buildTIFF(nil, []testEntry{rationalEntry(TagExposureTime, exifFormatFloat, 5, 2)}, nil)
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	exposure, ok = exif.ExposureTime()
+	assert.True(t, ok)
+	assert.Equal(t, "2.5 s", exposure)
+}
+
+func TestShutterSpeed(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	// ShutterSpeedValue is 74/10.
+	speed, ok := exif.ShutterSpeed()
+	assert.True(t, ok)
+	assert.InDelta(t, math.Pow(2, -7.4), speed, 1e-12)
+
+	_, ok = exif.ExposureTime()
+	assert.False(t, ok)
+}
+
+func TestApex(t *testing.T) {
+	// The APEX time and aperture value tables.
+	shutter := map[float64]float64{-2: 4, 0: 1, 1: 1.0 / 2, 5: 1.0 / 32, 10: 1.0 / 1024}
+	for tv, seconds := range shutter {
+		assert.InDelta(t, seconds, ApexToShutterSeconds(tv), 1e-12)
+	}
+
+	aperture := map[float64]float64{0: 1, 1: 1.4142, 2: 2, 3: 2.8284, 4: 4, 6: 8, 8: 16, 10: 32}
+	for av, fNumber := range aperture {
+		assert.InDelta(t, fNumber, ApexToFNumber(av), 1e-4)
+	}
+}
+
+func TestFNumberAndFocalLength(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	fNumber, ok := exif.FNumber()
+	assert.True(t, ok)
+	assert.Equal(t, 7.0, fNumber)
+
+	focalLength, ok := exif.FocalLength()
+	assert.True(t, ok)
+	assert.Equal(t, 9.9, focalLength)
+
+	_, ok = exif.FocalLength35mm()
+	assert.False(t, ok)
+}
+
+func TestFNumberFromApertureValue(t *testing.T) {
+	tiff := buildTIFF(nil, []testEntry{
+		rationalEntry(TagApertureValue, exifFormatFloat, 4, 1),
+		shortEntry(TagFocalLengthIn35mmFilm, 28),
+	}, nil)
+	exif, err := ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	fNumber, ok := exif.FNumber()
+	assert.True(t, ok)
+	assert.InDelta(t, 4.0, fNumber, 1e-12)
+
+	focalLength35mm, ok := exif.FocalLength35mm()
+	assert.True(t, ok)
+	assert.Equal(t, 28, focalLength35mm)
+}
+
+func TestISO(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	iso, ok := exif.ISO()
+	assert.True(t, ok)
+	assert.Equal(t, 125, iso)
+
+	tiff := buildTIFF(nil, []testEntry{
+		shortEntry(TagISOSpeedRatings, 0xFFFF),
+		longEntry(TagRecommendedExposureIndex, 102400),
+	}, nil)
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	iso, ok = exif.ISO()
+	assert.True(t, ok)
+	assert.Equal(t, 102400, iso)
+
+	tiff = buildTIFF(nil, []testEntry{shortEntry(TagISOSpeedRatings, 800, 1600)}, nil)
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	iso, ok = exif.ISO()
+	assert.True(t, ok)
+	assert.Equal(t, 800, iso)
+}
+
+func TestFlash(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	flash, ok := exif.Flash()
+	assert.True(t, ok)
+	assert.Equal(t, FlashInfo{FunctionPresent: true}, flash)
+
+	// Fired, auto mode, red-eye reduction.
+	tiff := buildTIFF(nil, []testEntry{shortEntry(TagFlash, 0x59)}, nil)
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	flash, ok = exif.Flash()
+	assert.True(t, ok)
+	assert.Equal(t, FlashInfo{Fired: true, Mode: FlashModeAuto, FunctionPresent: true, RedEyeReduction: true}, flash)
+	assert.Equal(t, 0x59, exif.Tags[TagFlash].(IntegerTag).IntValue())
+}
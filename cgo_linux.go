@@ -1,4 +1,5 @@
-// +build linux
+//go:build linux && !libexif_static
+// +build linux,!libexif_static
 
 package exif
 
@@ -0,0 +1,9 @@
+//go:build linux && libexif_static
+// +build linux,libexif_static
+
+package exif
+
+/*
+#cgo LDFLAGS: -Wl,-Bstatic -lexif -Wl,-Bdynamic -lm
+*/
+import "C"
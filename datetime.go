@@ -0,0 +1,39 @@
+package exif
+
+import (
+	"strings"
+	"time"
+)
+
+const dateTimeLayout = "2006:01:02 15:04:05"
+
+// DateTimeOriginal returns the time the image was taken. The fractional
+// seconds of SubSecTimeOriginal are added when present, so that burst shots
+// taken within the same second stay apart. EXIF records no time zone, the time
+// is returned in UTC.
+func (d *Data) DateTimeOriginal() (time.Time, bool) {
+	t, err := time.ParseInLocation(dateTimeLayout, d.textValue(TagDateTimeOriginal), time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t.Add(subSeconds(d.textValue(TagSubSecTimeOriginal))), true
+}
+
+// subSeconds converts the digits of a SubSecTime tag, the decimal fraction of
+// a second, to a duration. Anything but digits yields zero.
+func subSeconds(digits string) time.Duration {
+	digits = strings.TrimSpace(digits)
+	if digits == "" || len(digits) > 9 {
+		return 0
+	}
+	var fraction time.Duration
+	scale := time.Second
+	for _, c := range digits {
+		if c < '0' || c > '9' {
+			return 0
+		}
+		scale /= 10
+		fraction += time.Duration(c-'0') * scale
+	}
+	return fraction
+}
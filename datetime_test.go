@@ -0,0 +1,30 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDateTimeOriginal(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	dateTime, ok := exif.DateTimeOriginal()
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2000, 9, 2, 14, 30, 10, 0, time.UTC), dateTime)
+
+	for subSec, nanoseconds := range map[string]int{"42": 420000000, "123": 123000000, "5 ": 500000000, "x1": 0} {
+		tiff := buildTIFF(nil, []testEntry{
+			asciiEntry(TagDateTimeOriginal, "2014:04:27 18:15:04"),
+			asciiEntry(TagSubSecTimeOriginal, subSec),
+		}, nil)
+		exif, err := ReadBytes(buildJPEG(t, tiff))
+		assert.NoError(t, err)
+
+		dateTime, ok := exif.DateTimeOriginal()
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2014, 4, 27, 18, 15, 4, nanoseconds, time.UTC), dateTime)
+	}
+}
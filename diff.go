@@ -0,0 +1,51 @@
+package exif
+
+import "sort"
+
+// DiffKind describes how a tag differs between two Data sets.
+type DiffKind int
+
+const (
+	TagAdded DiffKind = iota + 1
+	TagRemoved
+	TagChanged
+)
+
+// TagDiff is a difference in a single tag of an IFD between two Data sets. Old
+// is empty for added tags and New is empty for removed tags.
+type TagDiff struct {
+	Ifd  int
+	Tag  int
+	Kind DiffKind
+	Old  string
+	New  string
+}
+
+// Diff returns the tags that were added, removed or changed (by text value)
+// going from a to b, ordered by IFD and then by tag id. Tags are compared IFD
+// by IFD, so a tag of IFD1 is never taken for the same tag of IFD0.
+func Diff(a, b *Data) []TagDiff {
+	var diffs []TagDiff
+	for ifd := 0; ifd < ifdCount; ifd++ {
+		for key, oldTag := range a.ifdTags[ifd] {
+			newTag, ok := b.ifdTags[ifd][key]
+			if !ok {
+				diffs = append(diffs, TagDiff{Ifd: ifd, Tag: key, Kind: TagRemoved, Old: oldTag.TextValue()})
+			} else if oldTag.TextValue() != newTag.TextValue() {
+				diffs = append(diffs, TagDiff{Ifd: ifd, Tag: key, Kind: TagChanged, Old: oldTag.TextValue(), New: newTag.TextValue()})
+			}
+		}
+		for key, newTag := range b.ifdTags[ifd] {
+			if _, ok := a.ifdTags[ifd][key]; !ok {
+				diffs = append(diffs, TagDiff{Ifd: ifd, Tag: key, Kind: TagAdded, New: newTag.TextValue()})
+			}
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Ifd != diffs[j].Ifd {
+			return diffs[i].Ifd < diffs[j].Ifd
+		}
+		return diffs[i].Tag < diffs[j].Tag
+	})
+	return diffs
+}
@@ -0,0 +1,31 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := New()
+	err := a.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	b := a.Clone()
+	assert.Equal(t, 0, len(Diff(a, b)))
+
+	b.Tags[TagMake].setTextValue("Other")
+	delete(b.Tags, TagModel)
+	delete(b.ifdTags[IFD0], TagModel)
+	b.storeTag(&basicTag{tag: TagGPSDateStamp, ifd: IFDGPS, value: "2014:04:27"})
+
+	// The Orientation of IFD1 is compared with its own, not with the one of
+	// IFD0 that Tags holds.
+	b.ifdTags[IFD1][TagOrientation].setTextValue("Bottom-right")
+
+	assert.Equal(t, []TagDiff{
+		{Ifd: IFD0, Tag: TagMake, Kind: TagChanged, Old: "FUJIFILM", New: "Other"},
+		{Ifd: IFD0, Tag: TagModel, Kind: TagRemoved, Old: "MX-1700ZOOM"},
+		{Ifd: IFD1, Tag: TagOrientation, Kind: TagChanged, Old: a.ifdTags[IFD1][TagOrientation].TextValue(), New: "Bottom-right"},
+		{Ifd: IFDGPS, Tag: TagGPSDateStamp, Kind: TagAdded, New: "2014:04:27"},
+	}, Diff(a, b))
+}
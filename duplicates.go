@@ -0,0 +1,120 @@
+package exif
+
+import "encoding/binary"
+
+// DuplicatePolicy decides which occurrence of a tag is kept when it appears
+// more than once in the same IFD, as it does in some malformed files.
+type DuplicatePolicy int
+
+const (
+	// DuplicateFirstWins keeps the first occurrence, the only one libexif
+	// reads. It is the default, and the later occurrences are not parsed.
+	DuplicateFirstWins DuplicatePolicy = iota
+	// DuplicateLastWins keeps the last occurrence.
+	DuplicateLastWins
+	// DuplicateCollectAll keeps the last occurrence in Tags, like
+	// DuplicateLastWins, and every occurrence in Duplicates.
+	DuplicateCollectAll
+)
+
+// WithDuplicatePolicy sets how tags appearing more than once in the same IFD
+// are handled.
+func WithDuplicatePolicy(p DuplicatePolicy) Option {
+	return func(d *Data) {
+		d.dupPolicy = p
+	}
+}
+
+// Duplicates returns every occurrence of the tags that appear more than once
+// in the given IFD, by tag, in the order they are stored. It is only filled
+// with the DuplicateCollectAll policy.
+func (d *Data) Duplicates(ifd int) map[int][]Tag {
+	duplicates := make(map[int][]Tag)
+	if ifd < 0 || ifd >= ifdCount {
+		return duplicates
+	}
+	for tag, occurrences := range d.duplicates[ifd] {
+		duplicates[tag] = append([]Tag(nil), occurrences...)
+	}
+	return duplicates
+}
+
+// resolveDuplicates applies the duplicate policy to the tags parsed from the
+// given TIFF structure. libexif only reads the first occurrence of a tag in
+// an IFD, so the later ones are parsed here one at a time.
+func (d *Data) resolveDuplicates(tiff []byte) error {
+	if d.dupPolicy == DuplicateFirstWins {
+		return nil
+	}
+	order, ok := tiffByteOrder(tiff)
+	if !ok {
+		return nil
+	}
+
+	for ifd, tags := range tiffDuplicates(tiff) {
+		for tag, entries := range tags {
+			first, ok := d.ifdTags[ifd][tag]
+			if !ok {
+				// The tag was not wanted or libexif dropped it.
+				continue
+			}
+			occurrences := []Tag{first}
+			for _, entry := range entries[1:] {
+				t, err := d.parseTIFFEntry(order, ifd, entry)
+				if err != nil {
+					return err
+				}
+				if t != nil {
+					occurrences = append(occurrences, t)
+				}
+			}
+			d.replaceTag(first, occurrences[len(occurrences)-1])
+
+			if d.dupPolicy == DuplicateCollectAll {
+				if d.duplicates[ifd] == nil {
+					d.duplicates[ifd] = make(map[int][]Tag)
+				}
+				d.duplicates[ifd][tag] = occurrences
+			}
+		}
+	}
+	return nil
+}
+
+// parseTIFFEntry parses a single entry of the given IFD the way the tags of
+// d are parsed, strict format checks included. The tag is nil if libexif
+// drops the entry.
+func (d *Data) parseTIFFEntry(order binary.ByteOrder, ifd int, entry tiffEntry) (Tag, error) {
+	single := &Data{
+		Tags:       make(map[int]Tag),
+		byteOrder:  d.byteOrder,
+		keepSpaces: d.keepSpaces,
+		tagNamer:   d.tagNamer,
+		strict:     d.strict,
+	}
+	payload := append([]byte(nil), exifHeader...)
+	err := single.parseExifPayload(append(payload, singleEntryTIFF(order, ifd, entry)...))
+	if err == ErrNoExifData {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return single.ifdTags[ifd][entry.tag], nil
+}
+
+// replaceTag puts tag in place of old in the tag maps and in the parse order.
+func (d *Data) replaceTag(old, tag Tag) {
+	if old == tag {
+		return
+	}
+	d.ifdTags[old.Ifd()][old.Tag()] = tag
+	if d.Tags[old.Tag()] == old {
+		d.Tags[old.Tag()] = tag
+	}
+	for i := range d.order {
+		if d.order[i] == old {
+			d.order[i] = tag
+		}
+	}
+}
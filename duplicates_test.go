@@ -0,0 +1,72 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// buildDuplicateTIFF encodes a TIFF structure whose IFD0 holds Make three
+// times, which is against the specification.
+func buildDuplicateTIFF() []byte {
+	return buildTIFF([]testEntry{
+		asciiEntry(TagMake, "First"),
+		asciiEntry(TagMake, "Second"),
+		asciiEntry(TagMake, "Third"),
+		asciiEntry(TagModel, "Model"),
+	}, nil, nil)
+}
+
+func TestDuplicatePolicy(t *testing.T) {
+	tiff := buildDuplicateTIFF()
+
+	exif, err := ReadBytes(tiff)
+	assert.NoError(t, err)
+	assert.Equal(t, "First", exif.Make())
+	assert.Equal(t, "Model", exif.Model())
+	assert.Empty(t, exif.Duplicates(IFD0))
+
+	exif = New(WithDuplicatePolicy(DuplicateLastWins))
+	assert.NoError(t, exif.ParseBytes(buildJPEG(t, tiff)))
+	assert.Equal(t, "Third", exif.Make())
+	assert.Empty(t, exif.Duplicates(IFD0))
+
+	exif = New(WithDuplicatePolicy(DuplicateCollectAll))
+	assert.NoError(t, exif.ParseBytes(buildJPEG(t, tiff)))
+	assert.Equal(t, "Third", exif.Make())
+	assert.Equal(t, "Third", exif.TagsInIFD(IFD0)[TagMake].TextValue())
+
+	duplicates := exif.Duplicates(IFD0)
+	assert.Len(t, duplicates, 1)
+	var values []string
+	for _, tag := range duplicates[TagMake] {
+		assert.Equal(t, IFD0, tag.Ifd())
+		values = append(values, tag.TextValue())
+	}
+	assert.Equal(t, []string{"First", "Second", "Third"}, values)
+
+	var makes []string
+	exif.Each(func(tag Tag) bool {
+		if tag.Tag() == TagMake {
+			makes = append(makes, tag.TextValue())
+		}
+		return true
+	})
+	assert.Equal(t, []string{"Third"}, makes)
+}
+
+func TestDuplicatePolicyStrict(t *testing.T) {
+	// The second Make is stored as a SHORT, which only the duplicate walk
+	// reads.
+	tiff := buildTIFF([]testEntry{
+		asciiEntry(TagMake, "First"),
+		shortEntry(TagMake, 1),
+	}, nil, nil)
+
+	exif := New(WithStrict())
+	assert.NoError(t, exif.ParseBytes(tiff))
+	assert.Equal(t, "First", exif.Make())
+
+	exif = New(WithStrict(), WithDuplicatePolicy(DuplicateLastWins))
+	err := exif.ParseBytes(tiff)
+	assert.Equal(t, &FormatError{Ifd: IFD0, Tag: TagMake, Format: exifFormatShort}, err)
+}
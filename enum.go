@@ -0,0 +1,116 @@
+package exif
+
+var colorSpaces = map[int]string{
+	1:      "sRGB",
+	0xFFFF: "Uncalibrated",
+}
+
+var exposurePrograms = map[int]string{
+	0: "Not defined",
+	1: "Manual",
+	2: "Normal program",
+	3: "Aperture priority",
+	4: "Shutter priority",
+	5: "Creative program",
+	6: "Action program",
+	7: "Portrait mode",
+	8: "Landscape mode",
+}
+
+var meteringModes = map[int]string{
+	0:   "Unknown",
+	1:   "Average",
+	2:   "Center weighted average",
+	3:   "Spot",
+	4:   "Multi spot",
+	5:   "Pattern",
+	6:   "Partial",
+	255: "Other",
+}
+
+var whiteBalances = map[int]string{
+	0: "Auto",
+	1: "Manual",
+}
+
+var sensingMethods = map[int]string{
+	1: "Not defined",
+	2: "One-chip color area sensor",
+	3: "Two-chip color area sensor",
+	4: "Three-chip color area sensor",
+	5: "Color sequential area sensor",
+	7: "Trilinear sensor",
+	8: "Color sequential linear sensor",
+}
+
+var exposureModes = map[int]string{
+	0: "Auto exposure",
+	1: "Manual exposure",
+	2: "Auto bracket",
+}
+
+var sceneCaptureTypes = map[int]string{
+	0: "Standard",
+	1: "Landscape",
+	2: "Portrait",
+	3: "Night scene",
+}
+
+// enumValue returns the name of the value of an enumerated tag, or false if
+// the tag is not present or holds a value that is not in names.
+func (d *Data) enumValue(tag int, names map[int]string) (string, bool) {
+	return enumName(d.Tags[tag], names)
+}
+
+// ifdEnumValue is like enumValue, but only looks for the tag in the given IFD.
+func (d *Data) ifdEnumValue(ifd, tag int, names map[int]string) (string, bool) {
+	return enumName(d.ifdTags[ifd][tag], names)
+}
+
+func enumName(tag Tag, names map[int]string) (string, bool) {
+	intTag, ok := tag.(IntegerTag)
+	if !ok {
+		return "", false
+	}
+	name, ok := names[intTag.IntValue()]
+	return name, ok
+}
+
+// ColorSpace returns "sRGB" or "Uncalibrated", the latter being used for
+// other color spaces such as Adobe RGB.
+func (d *Data) ColorSpace() (string, bool) {
+	return d.enumValue(TagColorSpace, colorSpaces)
+}
+
+// ExposureProgram returns the class of program used to set the exposure, like
+// "Manual" or "Aperture priority".
+func (d *Data) ExposureProgram() (string, bool) {
+	return d.enumValue(TagExposureProgram, exposurePrograms)
+}
+
+// MeteringMode returns the metering mode, like "Spot" or "Pattern".
+func (d *Data) MeteringMode() (string, bool) {
+	return d.enumValue(TagMeteringMode, meteringModes)
+}
+
+// WhiteBalance returns "Auto" or "Manual".
+func (d *Data) WhiteBalance() (string, bool) {
+	return d.enumValue(TagWhiteBalance, whiteBalances)
+}
+
+// SensingMethod returns the type of image sensor, like "One-chip color area
+// sensor".
+func (d *Data) SensingMethod() (string, bool) {
+	return d.enumValue(TagSensingMethod, sensingMethods)
+}
+
+// ExposureMode returns "Auto exposure", "Manual exposure" or "Auto bracket".
+func (d *Data) ExposureMode() (string, bool) {
+	return d.enumValue(TagExposureMode, exposureModes)
+}
+
+// SceneCaptureType returns the type of scene that was shot, like "Landscape"
+// or "Night scene".
+func (d *Data) SceneCaptureType() (string, bool) {
+	return d.enumValue(TagSceneCaptureType, sceneCaptureTypes)
+}
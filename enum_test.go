@@ -0,0 +1,64 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEnumerations(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	colorSpace, ok := exif.ColorSpace()
+	assert.True(t, ok)
+	assert.Equal(t, "sRGB", colorSpace)
+
+	program, ok := exif.ExposureProgram()
+	assert.True(t, ok)
+	assert.Equal(t, "Normal program", program)
+
+	metering, ok := exif.MeteringMode()
+	assert.True(t, ok)
+	assert.Equal(t, "Pattern", metering)
+
+	_, ok = exif.WhiteBalance()
+	assert.False(t, ok)
+
+	sensing, ok := exif.SensingMethod()
+	assert.True(t, ok)
+	assert.Equal(t, "One-chip color area sensor", sensing)
+
+	_, ok = exif.ExposureMode()
+	assert.False(t, ok)
+
+	tiff := buildTIFF(nil, []testEntry{
+		shortEntry(TagMeteringMode, 42),
+		shortEntry(TagColorSpace, 0xFFFF),
+		shortEntry(TagWhiteBalance, 1),
+		shortEntry(TagExposureMode, 2),
+		shortEntry(TagSceneCaptureType, 3),
+	}, nil)
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	colorSpace, ok = exif.ColorSpace()
+	assert.True(t, ok)
+	assert.Equal(t, "Uncalibrated", colorSpace)
+
+	whiteBalance, ok := exif.WhiteBalance()
+	assert.True(t, ok)
+	assert.Equal(t, "Manual", whiteBalance)
+
+	exposureMode, ok := exif.ExposureMode()
+	assert.True(t, ok)
+	assert.Equal(t, "Auto bracket", exposureMode)
+
+	sceneType, ok := exif.SceneCaptureType()
+	assert.True(t, ok)
+	assert.Equal(t, "Night scene", sceneType)
+
+	// Values outside of the enumeration are reported as absent.
+	_, ok = exif.MeteringMode()
+	assert.False(t, ok)
+}
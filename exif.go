@@ -26,16 +26,23 @@ package exif
 #include <stdlib.h>
 #include <libexif/exif-data.h>
 #include <libexif/exif-loader.h>
+#include <libexif/exif-mnote-data.h>
 #include "_cgo/types.h"
 
 exif_value_t* pop_exif_value(exif_stack_t *);
+void reverse_exif_stack(exif_stack_t *);
 void free_exif_value(exif_value_t* n);
 exif_stack_t* exif_dump(ExifData *);
 */
 import "C"
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
 	"errors"
+	"io"
+	"os"
 	"runtime"
 	"strings"
 	"unsafe"
@@ -43,11 +50,71 @@ import (
 
 // Error messages.
 var (
-	ErrNoExifData      = errors.New(`No EXIF data found.`)
+	ErrNoExifData         = errors.New(`No EXIF data found.`)
+	ErrNotJPEG            = errors.New(`Not a JPEG image.`)
+	ErrMalformedJPEG      = errors.New(`Malformed JPEG segment structure.`)
+	ErrMalformedExif      = errors.New(`Malformed EXIF structure.`)
+	ErrSegmentTooLarge    = errors.New(`JPEG segment exceeds the maximum size.`)
+	ErrInvalidOrientation = errors.New(`Orientation must be between 1 and 8.`)
+	ErrMaxBytesExceeded   = errors.New(`Maximum number of bytes written without finding EXIF data.`)
+	ErrTruncatedExif      = errors.New(`EXIF data is truncated.`)
+	ErrEncodeExif         = errors.New(`Could not encode EXIF data.`)
+	ErrLibexifUnavailable = errors.New(`libexif is not available.`)
+
+	// ErrFoundExifInData is no longer returned by Write.
+	//
+	// Deprecated: Done reports when Parse can be called.
 	ErrFoundExifInData = errors.New(`Found EXIF header. OK to call Parse.`)
 )
 
+const IFD0 = 0
+const IFD1 = 1
+const IFDExif = 2
+const IFDGPS = 3
+const IFDInteroperability = 4
+const ifdCount = 5
+
+const TagImageWidth = 256
+const TagImageLength = 257
+const TagCompression = 259
+const TagPhotometricInterpretation = 262
+const TagMake = 271
+const TagModel = 272
 const TagOrientation = 274
+const TagSamplesPerPixel = 277
+const TagXResolution = 282
+const TagYResolution = 283
+const TagPlanarConfiguration = 284
+const TagResolutionUnit = 296
+const TagExposureTime = 33434
+const TagFNumber = 33437
+const TagExposureProgram = 34850
+const TagISOSpeedRatings = 34855
+const TagRecommendedExposureIndex = 34866
+const TagISOSpeed = 34867
+const TagDateTimeOriginal = 36867
+const TagShutterSpeedValue = 37377
+const TagApertureValue = 37378
+const TagMeteringMode = 37383
+const TagFlash = 37385
+const TagFocalLength = 37386
+const TagMakerNote = 37500
+const TagSubSecTimeOriginal = 37521
+const TagXPTitle = 40091
+const TagXPComment = 40092
+const TagXPAuthor = 40093
+const TagXPKeywords = 40094
+const TagXPSubject = 40095
+const TagColorSpace = 40961
+const TagPixelXDimension = 40962
+const TagPixelYDimension = 40963
+const TagSensingMethod = 41495
+const TagExposureMode = 41986
+const TagWhiteBalance = 41987
+const TagFocalLengthIn35mmFilm = 41989
+const TagSceneCaptureType = 41990
+const TagLensMake = 42035
+const TagLensModel = 42036
 
 const TagLatitudeRef = 1
 const TagLatitude = 2
@@ -55,6 +122,14 @@ const TagLongitudeRef = 3
 const TagLongitude = 4
 const TagAltitudeRef = 5
 const TagAltitude = 6
+const TagGPSTimeStamp = 7
+const TagGPSSpeedRef = 12
+const TagGPSSpeed = 13
+const TagGPSTrackRef = 14
+const TagGPSTrack = 15
+const TagGPSImgDirectionRef = 16
+const TagGPSImgDirection = 17
+const TagGPSDateStamp = 29
 
 const LatitudeRefNorth = "N"
 const LatitudeRefSouth = "S"
@@ -73,48 +148,107 @@ const OrientationRightTop = 6
 const OrientationRightBottom = 7
 const OrientationLeftBottom = 8
 
+const readChunkSize = 4096
+
+// makerNoteValueSize is the size of the buffer maker note values are rendered
+// into.
+const makerNoteValueSize = 1024
+
+// readHeaderSize is the number of bytes needed to tell image formats apart.
+const readHeaderSize = 12
+
+// maxExifSize is the most EXIF data libexif reads, the size of a JPEG APP1
+// segment.
+const maxExifSize = 0xFFFE
+
 const exifFormatByte = 1
 const exifFormatString = 2
 const exifFormatShort = 3
 const exifFormatLong = 4
 const exifFormatFloat = 5
+const exifFormatSByte = 6
+const exifFormatUndefined = 7
+const exifFormatSShort = 8
+const exifFormatSLong = 9
+const exifFormatSRational = 10
 
 type Tag interface {
 	Tag() int
 	TextLabel() string
 	TextValue() string
+	Description() string
+	Ifd() int
+	// Value returns the value of the tag as a Go type: an int for integer
+	// tags with one component and a []int for those with several, a float64
+	// for rational tags with one component and a []float64 for those with
+	// several, a []byte for UNDEFINED tags and a string otherwise.
+	Value() interface{}
 	setTag(int)
 	setTextLabel(string)
 	setTextValue(string)
+	setDescription(string)
+	setIfd(int)
 }
 
 type IntegerTag interface {
 	Tag
 	IntValue() int
+	IntValues() []int
+}
+
+type StringTag interface {
+	Tag
+	StringValues() []string
 }
 
 type FloatTag interface {
 	Tag
 	FloatValue() float64
+	FloatValues() []float64
 	Numerator() int
 	Denominator() int
 }
 
 type basicTag struct {
-	tag   int
-	label string
-	value string
+	tag         int
+	label       string
+	value       string
+	description string
+	ifd         int
+	data        []byte
 }
 
 type integerTag struct {
 	basicTag
-	intValue int
+	intValue  int
+	intValues []int
+}
+
+type stringTag struct {
+	basicTag
+	values []string
 }
 
 type floatTag struct {
 	basicTag
 	numerator   int
 	denominator int
+	components  []rationalComponent
+}
+
+type rationalComponent struct {
+	numerator   int
+	denominator int
+}
+
+// RawEntry is an EXIF entry as stored in the file, before any interpretation.
+// Data holds the Components values of the entry in the file's byte order.
+type RawEntry struct {
+	Ifd        int
+	Tag        int
+	Format     int
+	Components int
+	Data       []byte
 }
 
 func (this *basicTag) Tag() int {
@@ -127,6 +261,25 @@ func (this *basicTag) TextValue() string {
 	return this.value
 }
 
+// Description returns libexif's long-form description of the tag, or an
+// empty string if libexif does not know the tag.
+func (this *basicTag) Description() string {
+	return this.description
+}
+
+// Ifd returns the IFD the tag was read from, one of IFD0, IFD1, IFDExif,
+// IFDGPS or IFDInteroperability.
+func (this *basicTag) Ifd() int {
+	return this.ifd
+}
+
+func (this *basicTag) Value() interface{} {
+	if this.data != nil {
+		return append([]byte(nil), this.data...)
+	}
+	return this.value
+}
+
 func (this *basicTag) setTag(val int) {
 	this.tag = val
 }
@@ -136,9 +289,43 @@ func (this *basicTag) setTextLabel(val string) {
 func (this *basicTag) setTextValue(val string) {
 	this.value = val
 }
+func (this *basicTag) setDescription(val string) {
+	this.description = val
+}
+func (this *basicTag) setIfd(val int) {
+	this.ifd = val
+}
+
+// IntValue returns the first component of the tag. Tags with several
+// components, such as BitsPerSample, are read with IntValues.
 func (this *integerTag) IntValue() int {
 	return this.intValue
 }
+
+// IntValues returns the value of each component of the tag, in the order they
+// are stored.
+func (this *integerTag) IntValues() []int {
+	return append([]int(nil), this.intValues...)
+}
+
+func (this *integerTag) Value() interface{} {
+	if len(this.intValues) > 1 {
+		return this.IntValues()
+	}
+	return this.intValue
+}
+
+// Value returns the strings of the tag, separated by NULs as they are stored.
+func (this *stringTag) Value() interface{} {
+	return strings.Join(this.values, "\x00")
+}
+
+// StringValues returns the NUL-separated strings stored in the tag, with
+// surrounding spaces trimmed and empty strings omitted.
+func (this *stringTag) StringValues() []string {
+	return this.values
+}
+
 func (this *floatTag) Numerator() int {
 	return this.numerator
 }
@@ -146,47 +333,996 @@ func (this *floatTag) Denominator() int {
 	return this.denominator
 }
 
+// FloatValue returns the value of the first rational component of the tag.
+// Tags with several components, such as GPS coordinates in degrees, minutes
+// and seconds, are read with FloatValues.
 func (this *floatTag) FloatValue() float64 {
 	return (float64(this.numerator) / float64(this.denominator))
 }
 
+func (this *floatTag) Value() interface{} {
+	if len(this.components) == 1 {
+		return this.FloatValue()
+	}
+	return this.FloatValues()
+}
+
+// FloatValues returns the value of each rational component of the tag, in
+// the order they are stored.
+func (this *floatTag) FloatValues() []float64 {
+	values := make([]float64, len(this.components))
+	for i, component := range this.components {
+		values[i] = float64(component.numerator) / float64(component.denominator)
+	}
+	return values
+}
+
 // Data stores the EXIF tags of a file.
 type Data struct {
 	exifLoader *C.ExifLoader
 	Tags       map[int]Tag
+	ifdTags    [ifdCount]map[int]Tag
+	order      []Tag
+	readBuf    []byte
+	headerBuf  []byte
+	readLoader *C.ExifLoader
+	readLogs   bool
+	keepLoader bool
+	unhandled  map[int]int
+	keepRaw    bool
+	byteOrder  binary.ByteOrder
+	makerNotes map[string]string
+	hasNotes   bool
+	keepSpaces bool
+	xmp        []byte
+	xmpHead    []byte
+	xmpScan    int
+	xmpDone    bool
+	keepXMP    bool
+	dupPolicy  DuplicatePolicy
+	duplicates [ifdCount]map[int][]Tag
+	exifData   *C.ExifData
+	want       map[int]bool
+	strict     bool
+	stop       func(Tag) bool
+	loaderDone bool
+	tagNamer   func(ifd, tag int) (string, bool)
+	maxBytes   int
+	written    int
+}
+
+// Option configures a Data object created with New.
+type Option func(*Data)
+
+// WithMaxBytes limits the number of bytes Write passes to the exif loader.
+// Once n bytes have been written without the loader finishing, Write returns
+// ErrMaxBytesExceeded. A value of 0 means no limit. It also bounds the EXIF
+// item of a HEIF image when its size is left to the end of the file; such an
+// item is only read with a limit.
+func WithMaxBytes(n int) Option {
+	return func(d *Data) {
+		d.maxBytes = n
+	}
+}
+
+// WithKeepRaw keeps the parsed libexif data alive until Close is called, so
+// that Thumbnail, RawEntries and WriteTo can read from it after parsing.
+// Nothing is copied out of it until they are called.
+func WithKeepRaw() Option {
+	return func(d *Data) {
+		d.keepRaw = true
+	}
+}
+
+// WithUntrimmedValues keeps the spaces around tag values, which are trimmed
+// by default. Spaces can be meaningful in free text tags such as
+// ImageDescription or Artist. Labels are trimmed either way.
+func WithUntrimmedValues() Option {
+	return func(d *Data) {
+		d.keepSpaces = true
+	}
 }
 
 // New creates and returns a new exif.Data object.
-func New() *Data {
+func New(opts ...Option) *Data {
 	data := &Data{
 		Tags: make(map[int]Tag),
 	}
+	for _, opt := range opts {
+		opt(data)
+	}
 	return data
 }
 
-// Read attempts to read EXIF data from a file.
+// Read attempts to read EXIF data from a file. If the data is truncated, the
+// tags that could be read are returned along with ErrTruncatedExif.
 func Read(file string) (*Data, error) {
 	data := New()
 	if err := data.Open(file); err != nil {
+		if err == ErrTruncatedExif {
+			return data, err
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// ReadBytes attempts to read EXIF data from an in-memory image. If the data is
+// truncated, the tags that could be read are returned along with
+// ErrTruncatedExif.
+func ReadBytes(b []byte) (*Data, error) {
+	data := New()
+	if err := data.ParseBytes(b); err != nil {
+		if err == ErrTruncatedExif {
+			return data, err
+		}
 		return nil, err
 	}
 	return data, nil
 }
 
+// HasExif reports whether the given file holds EXIF data, without parsing
+// its tags. It is cheaper than Read when only the answer is needed.
+func HasExif(file string) (bool, error) {
+	if !libexifAvailable() {
+		return false, ErrLibexifUnavailable
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return false, &FileError{Path: file, Err: err}
+	}
+	defer f.Close()
+
+	header := make([]byte, readHeaderSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, &FileError{Path: file, Err: err}
+	}
+	header = header[:n]
+
+	if format := detectFormat(header); format != formatStream {
+		src, ok := fileSource(f, 0)
+		if !ok {
+			// Pipes and the like cannot be read at an offset.
+			rest, err := io.ReadAll(f)
+			if err != nil {
+				return false, &FileError{Path: file, Err: err}
+			}
+			src = bytesSource(append(header, rest...))
+		}
+		has := sourceHasExif(format, src)
+		if src.err != nil {
+			return false, &FileError{Path: file, Err: src.err}
+		}
+		return has, nil
+	}
+
+	loader := newLoader()
+	defer C.exif_loader_unref(loader)
+
+	if n > 0 && C.exif_loader_write(loader, (*C.uchar)(unsafe.Pointer(&header[0])), C.uint(n)) == 0 {
+		return len(loaderPayload(loader)) > 0, nil
+	}
+
+	buf := make([]byte, readChunkSize)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 && C.exif_loader_write(loader, (*C.uchar)(unsafe.Pointer(&buf[0])), C.uint(n)) == 0 {
+			break
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, &FileError{Path: file, Err: err}
+		}
+	}
+
+	return len(loaderPayload(loader)) > 0, nil
+}
+
+// HasExifBytes reports whether the given image holds EXIF data, without
+// parsing its tags.
+func HasExifBytes(b []byte) bool {
+	if !libexifAvailable() {
+		return false
+	}
+	if format := detectFormat(b); format != formatStream {
+		return sourceHasExif(format, bytesSource(b))
+	}
+
+	loader := newLoader()
+	defer C.exif_loader_unref(loader)
+	if len(b) > 0 {
+		C.exif_loader_write(loader, (*C.uchar)(unsafe.Pointer(&b[0])), C.uint(len(b)))
+	}
+	return len(loaderPayload(loader)) > 0
+}
+
+// sourceHasExif reports whether an image in one of the formats read as a
+// whole holds EXIF data. Reading stops at the IFD0 of a TIFF based image, the
+// eXIf chunk of a PNG image, the EXIF chunk of a WebP image or the Exif item
+// of a HEIF image; the tags themselves are never read.
+func sourceHasExif(format imageFormat, src *source) bool {
+	switch format {
+	case formatTIFF:
+		return tiffHasIFD0(src)
+	case formatPNG:
+		_, _, ok := pngExif(src)
+		return ok
+	case formatWebP:
+		_, _, ok := webpExif(src)
+		return ok
+	case formatHEIF:
+		return heifHasExif(src)
+	}
+	return false
+}
+
+// FileError is returned when a file cannot be opened or read. The file might
+// hold EXIF data or not; ErrNoExifData is only returned for files that were
+// read without finding any.
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return `Could not read ` + e.Path + `: ` + e.Err.Error()
+}
+
+// Unwrap returns the underlying error, typically an *os.PathError.
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
 // Open opens a file path and loads its EXIF data.
 func (d *Data) Open(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return &FileError{Path: file, Err: err}
+	}
+	defer f.Close()
+
+	return d.ReadFile(f)
+}
+
+// OpenTags is like Open, but only the given tags are stored; all others are
+// skipped while parsing.
+func (d *Data) OpenTags(file string, want []int) error {
+	d.want = make(map[int]bool, len(want))
+	for _, tag := range want {
+		d.want[tag] = true
+	}
+	defer func() {
+		d.want = nil
+	}()
+
+	return d.Open(file)
+}
+
+// OpenUntil is like Open, but parsing stops right after the first tag for
+// which stop returns true. Tags are visited in the order libexif emits them,
+// IFD0 first, so a tag such as Orientation can be read without parsing the
+// rest.
+func (d *Data) OpenUntil(file string, stop func(Tag) bool) error {
+	d.stop = stop
+	defer func() {
+		d.stop = nil
+	}()
+
+	return d.Open(file)
+}
+
+// OpenContext is like Open, but the file is read incrementally and reading
+// stops with ctx.Err() if ctx is done before the EXIF data has been loaded.
+func (d *Data) OpenContext(ctx context.Context, file string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return &FileError{Path: file, Err: err}
+	}
+	defer f.Close()
+
+	type chunk struct {
+		b   []byte
+		err error
+	}
+	chunks := make(chan chunk)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			buf := make([]byte, readChunkSize)
+			n, err := f.Read(buf)
+			select {
+			case chunks <- chunk{buf[:n], err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	loader := newLoader()
+	defer C.exif_loader_unref(loader)
+	d.resetXMP()
+	defer d.finishXMP()
+
+	var whole []byte
+	var parseWhole func(*source) error
+	var fedLoader bool
+	// Once the loader is done, reading goes on only to find the XMP segment.
+	var loaderDone bool
+	var parseErr error
+	for {
+		select {
+		case <-ctx.Done():
+			if loaderDone {
+				return parseErr
+			}
+			return ctx.Err()
+		case c := <-chunks:
+			if parseWhole == nil && len(c.b) > 0 && !fedLoader {
+				if parseWhole = d.wholeFileParser(c.b); parseWhole != nil {
+					if src, ok := fileSource(f, 0); ok {
+						return d.parseSource(f.Name(), parseWhole, src)
+					}
+				}
+			}
+			if parseWhole != nil {
+				whole = append(whole, c.b...)
+			} else if len(c.b) > 0 {
+				fedLoader = true
+				more := d.scanXMP(c.b)
+				if !loaderDone && C.exif_loader_write(loader, (*C.uchar)(unsafe.Pointer(&c.b[0])), C.uint(len(c.b))) == 0 {
+					loaderDone = true
+					parseErr = d.parseLoader(loader, true)
+				}
+				if loaderDone && !more {
+					return parseErr
+				}
+			}
+			if loaderDone && c.err != nil {
+				return parseErr
+			}
+			if c.err == io.EOF {
+				if parseWhole != nil {
+					return parseWhole(bytesSource(whole))
+				}
+				return d.parseLoader(loader, false)
+			}
+			if c.err != nil {
+				return &FileError{Path: file, Err: c.err}
+			}
+		}
+	}
+}
+
+// ReadFile loads the EXIF data of an already open file, reading from its
+// current offset. The file is not closed.
+func (d *Data) ReadFile(f *os.File) error {
+	header := d.headerBuffer()
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return &FileError{Path: f.Name(), Err: err}
+	}
+	header = header[:n]
+	d.resetXMP()
+
+	if parseWhole := d.wholeFileParser(header); parseWhole != nil {
+		d.finishXMP()
+		var src *source
+		if offset, err := f.Seek(0, io.SeekCurrent); err == nil {
+			src, _ = fileSource(f, offset-int64(n))
+		}
+		if src == nil {
+			// Pipes and the like cannot be read at an offset.
+			rest, err := io.ReadAll(f)
+			if err != nil {
+				return &FileError{Path: f.Name(), Err: err}
+			}
+			src = bytesSource(append(header[:n:n], rest...))
+		}
+		return d.parseSource(f.Name(), parseWhole, src)
+	}
+
+	loader := d.fileLoader()
+	defer d.releaseFileLoader(loader)
+
+	d.scanXMP(header)
+	done := n == 0 || C.exif_loader_write(loader, (*C.uchar)(unsafe.Pointer(&header[0])), C.uint(n)) == 0
+
+	buf := d.readBuffer()
+	for !done {
+		n, err := f.Read(buf)
+		if n > 0 {
+			d.scanXMP(buf[:n])
+			done = C.exif_loader_write(loader, (*C.uchar)(unsafe.Pointer(&buf[0])), C.uint(n)) == 0
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &FileError{Path: f.Name(), Err: err}
+		}
+	}
+	err = d.parseLoader(loader, done)
+
+	// The XMP segment usually follows the EXIF one; when it is wanted, read on
+	// until it is found or the header of the image ends.
+	for d.scanXMP(nil) {
+		n, rerr := f.Read(buf)
+		d.scanXMP(buf[:n])
+		if rerr != nil {
+			break
+		}
+	}
+	d.finishXMP()
+
+	return err
+}
+
+// readBuffer returns the buffer ReadFile reads chunks into, which is kept for
+// the next file.
+func (d *Data) readBuffer() []byte {
+	if d.readBuf == nil {
+		d.readBuf = make([]byte, readChunkSize)
+	}
+	return d.readBuf
+}
+
+// headerBuffer returns the buffer ReadFile reads the header of a file into,
+// which is kept for the next file.
+func (d *Data) headerBuffer() []byte {
+	if d.headerBuf == nil {
+		d.headerBuf = make([]byte, readHeaderSize)
+	}
+	return d.headerBuf
+}
+
+// fileLoader returns the exif loader ReadFile feeds. A Parser keeps its loader
+// from one file to the next, unless logging was turned on or off since it was
+// created; every other Data creates one per file.
+func (d *Data) fileLoader() *C.ExifLoader {
+	if !d.keepLoader {
+		return newLoader()
+	}
+	if d.readLoader != nil && d.readLogs != logging() {
+		C.exif_loader_unref(d.readLoader)
+		d.readLoader = nil
+	}
+	if d.readLoader == nil {
+		d.readLoader = newLoader()
+		d.readLogs = logging()
+		d.setFinalizer()
+	}
+	return d.readLoader
+}
+
+// releaseFileLoader is done with a loader returned by fileLoader: the one a
+// Parser keeps is reset for the next file, any other is released.
+func (d *Data) releaseFileLoader(loader *C.ExifLoader) {
+	if loader == d.readLoader {
+		C.exif_loader_reset(loader)
+		return
+	}
+	C.exif_loader_unref(loader)
+}
+
+// ParseBytes loads the EXIF data of an in-memory image.
+func (d *Data) ParseBytes(b []byte) error {
+	d.findXMP(b)
+
+	if parseWhole := d.wholeFileParser(b); parseWhole != nil {
+		return parseWhole(bytesSource(b))
+	}
+
+	loader := newLoader()
+	defer C.exif_loader_unref(loader)
+
+	done := len(b) > 0 && C.exif_loader_write(loader, (*C.uchar)(unsafe.Pointer(&b[0])), C.uint(len(b))) == 0
+
+	return d.parseLoader(loader, done)
+}
+
+// ParseWithByteOrder parses b like ParseBytes, but reads SHORT, LONG and
+// RATIONAL values in the given byte order instead of the one the data
+// declares. When b is neither a JPEG stream nor another container read by
+// ParseBytes, but a bare TIFF structure whose header is damaged, the header
+// is rebuilt for that byte order.
+func (d *Data) ParseWithByteOrder(b []byte, order binary.ByteOrder) error {
+	d.byteOrder = order
+	defer func() {
+		d.byteOrder = nil
+	}()
+
+	if detectFormat(b) == formatStream && !bytes.HasPrefix(b, []byte{jpegMarkerPrefix}) {
+		tiff := bytes.TrimPrefix(b, exifHeader)
+		if _, ok := tiffByteOrder(tiff); !ok && len(tiff) >= 8 {
+			repaired := append(tiffHeader(order), tiff[4:]...)
+			return d.parseTIFF(bytesSource(repaired))
+		}
+	}
+	return d.ParseBytes(b)
+}
+
+// imageFormat is the container format of an image, as far as reading its
+// EXIF data is concerned.
+type imageFormat int
+
+const (
+	// formatStream is JPEG, or anything else, streamed to the exif loader.
+	formatStream imageFormat = iota
+	formatTIFF
+	formatPNG
+	formatWebP
+	formatHEIF
+)
+
+// detectFormat tells the format of an image from its first bytes. The header
+// must hold at least the first readHeaderSize bytes of the file, if the file
+// is that long.
+func detectFormat(header []byte) imageFormat {
+	if _, ok := tiffByteOrder(header); ok {
+		return formatTIFF
+	}
+	if bytes.HasPrefix(header, pngSignature) {
+		return formatPNG
+	}
+	if isWebP(header) {
+		return formatWebP
+	}
+	if isHEIF(header) {
+		return formatHEIF
+	}
+	return formatStream
+}
+
+// wholeFileParser returns the parser for image formats whose EXIF data has to
+// be located in the whole file instead of being streamed to the exif loader,
+// or nil for formats the loader handles. The header is the one passed to
+// detectFormat.
+func (d *Data) wholeFileParser(header []byte) func(*source) error {
+	switch detectFormat(header) {
+	case formatTIFF:
+		return d.parseTIFF
+	case formatPNG:
+		return d.parsePNG
+	case formatWebP:
+		return d.parseWebP
+	case formatHEIF:
+		return d.parseHEIF
+	}
+	return nil
+}
+
+// parseSource runs a parser returned by wholeFileParser on the image read from
+// path, reporting read errors as a *FileError.
+func (d *Data) parseSource(path string, parse func(*source) error, src *source) error {
+	err := parse(src)
+	if src.err != nil {
+		return &FileError{Path: path, Err: src.err}
+	}
+	return err
+}
+
+// parseTIFF loads the EXIF data of a TIFF based image, such as DNG and most
+// other raw formats, where the EXIF structure is the file itself. libexif
+// reads no further than 64KB into EXIF data, so the metadata IFDs are first
+// gathered into a compact copy of the structure; image data is never read.
+func (d *Data) parseTIFF(src *source) error {
+	d.startParse()
+	// The compact copy is never cut short, so check the original.
+	truncated := tiffTruncated(src)
+	tiff, movedNote, err := compactTIFF(src, d.keepTIFFTag, maxExifSize-len(exifHeader))
+	if err != nil {
+		if truncated {
+			return ErrTruncatedExif
+		}
+		return ErrNoExifData
+	}
+
+	payload := make([]byte, 0, len(exifHeader)+len(tiff))
+	payload = append(payload, exifHeader...)
+	err = d.parseExifPayload(append(payload, tiff...))
+	if movedNote {
+		// The offsets within the maker note may point to where it was.
+		d.hasNotes = false
+	}
+	if truncated && (err == nil || err == ErrNoExifData) {
+		return ErrTruncatedExif
+	}
+	return err
+}
 
-	cfile := C.CString(file)
-	defer C.free(unsafe.Pointer(cfile))
+// keepTIFFTag reports whether a TIFF entry should survive compactTIFF: libexif
+// would drop tags it does not know anyway, unless a tag namer is set.
+func (d *Data) keepTIFFTag(ifd, tag int) bool {
+	return d.tagNamer != nil || C.exif_tag_get_name_in_ifd(C.ExifTag(tag), C.ExifIfd(ifd)) != nil
+}
 
-	exifData := C.exif_data_new_from_file(cfile)
+// parseExifPayload loads EXIF data that starts with the "Exif\0\0" header,
+// as found in a JPEG APP1 segment. A payload whose TIFF header or IFD0 is cut
+// short returns ErrTruncatedExif; the tags read from it are kept but may be
+// incomplete. Values pointing past the end of the payload are skipped without
+// an error.
+func (d *Data) parseExifPayload(b []byte) error {
+	d.startParse()
+	if !libexifAvailable() {
+		return ErrLibexifUnavailable
+	}
+	if len(b) == 0 {
+		return ErrNoExifData
+	}
+	tiff := bytes.TrimPrefix(b, exifHeader)
+	truncated := tiffTruncated(bytesSource(tiff))
 
+	exifData := d.loadExifData((*C.uchar)(unsafe.Pointer(&b[0])), C.uint(len(b)))
 	if exifData == nil {
+		if truncated {
+			return ErrTruncatedExif
+		}
 		return ErrNoExifData
 	}
 	defer C.exif_data_unref(exifData)
+	if d.keepRaw {
+		d.releaseExifData()
+		C.exif_data_ref(exifData)
+		d.exifData = exifData
+		d.setFinalizer()
+	}
+
+	// The dump of data without any entry is empty, which parseExifData
+	// handles like any other.
+	if err := d.parseExifData(exifData); err != nil {
+		return err
+	}
+	if err := d.resolveDuplicates(tiff); err != nil {
+		return err
+	}
+	if truncated {
+		return ErrTruncatedExif
+	}
+	for _, content := range exifData.ifd {
+		if content != nil && content.count > 0 {
+			return nil
+		}
+	}
+	return ErrNoExifData
+}
+
+// startParse forgets the tag order and the libexif data of the previous
+// parse, so that Each, RawEntries and WriteTo only see the data being parsed.
+func (d *Data) startParse() {
+	for i := range d.order {
+		d.order[i] = nil
+	}
+	d.order = d.order[:0]
+	d.releaseExifData()
+}
+
+// loaderPayload returns a copy of the EXIF data collected by loader, or nil if
+// the loader found none. It stands in for exif_loader_get_data so that the
+// data options can be set before loading.
+func loaderPayload(loader *C.ExifLoader) []byte {
+	var buf *C.uchar
+	var size C.uint
+	C.exif_loader_get_buf(loader, &buf, &size)
+	if buf == nil || size == 0 {
+		return nil
+	}
+
+	return C.GoBytes(unsafe.Pointer(buf), C.int(size))
+}
+
+// loadExifData loads raw EXIF data into a new ExifData.
+func (d *Data) loadExifData(buf *C.uchar, size C.uint) *C.ExifData {
+	exifData := C.exif_data_new()
+	if exifData == nil {
+		return nil
+	}
+	attachLog(exifData)
+	if d.tagNamer != nil {
+		// Keep tags libexif does not know, which it otherwise drops both
+		// while loading and while fixing the data up to the specification.
+		C.exif_data_unset_option(exifData, C.EXIF_DATA_OPTION_IGNORE_UNKNOWN_TAGS)
+		C.exif_data_unset_option(exifData, C.EXIF_DATA_OPTION_FOLLOW_SPECIFICATION)
+	}
+	if d.strict {
+		// Check the formats as stored rather than as fixed by libexif.
+		C.exif_data_unset_option(exifData, C.EXIF_DATA_OPTION_FOLLOW_SPECIFICATION)
+	}
+	C.exif_data_load_data(exifData, buf, size)
+	return exifData
+}
 
-	return d.parseExifData(exifData)
+// parseLoader parses the EXIF data collected by loader. done tells whether the
+// loader got the whole APP1 segment; when the input ended before it did, the
+// tags read are kept and ErrTruncatedExif is returned.
+func (d *Data) parseLoader(loader *C.ExifLoader, done bool) error {
+	payload := loaderPayload(loader)
+	err := d.parseExifPayload(payload)
+	if !done && len(payload) > 0 && (err == nil || err == ErrNoExifData) {
+		return ErrTruncatedExif
+	}
+	return err
+}
+
+// Clone returns a deep copy of d's tags. The copy does not share d's loader.
+func (d *Data) Clone() *Data {
+	clone := New()
+	clones := make(map[Tag]Tag, len(d.Tags))
+	cloneOnce := func(tag Tag) Tag {
+		if c, ok := clones[tag]; ok {
+			return c
+		}
+		c := cloneTag(tag)
+		clones[tag] = c
+		return c
+	}
+	for key, tag := range d.Tags {
+		clone.Tags[key] = cloneOnce(tag)
+	}
+	for ifd, tags := range d.ifdTags {
+		if tags == nil {
+			continue
+		}
+		clone.ifdTags[ifd] = make(map[int]Tag, len(tags))
+		for key, tag := range tags {
+			clone.ifdTags[ifd][key] = cloneOnce(tag)
+		}
+	}
+	for _, tag := range d.order {
+		clone.order = append(clone.order, cloneOnce(tag))
+	}
+	clone.unhandled = d.UnhandledFormats()
+	clone.xmp, _ = d.RawXMP()
+	if d.exifData != nil {
+		// libexif data is never modified once loaded, so it is shared.
+		C.exif_data_ref(d.exifData)
+		clone.exifData = d.exifData
+		clone.keepRaw = true
+		clone.setFinalizer()
+	}
+	clone.makerNotes, clone.hasNotes = d.MakerNotes()
+	for ifd, tags := range d.duplicates {
+		if tags == nil {
+			continue
+		}
+		clone.duplicates[ifd] = make(map[int][]Tag, len(tags))
+		for key, occurrences := range tags {
+			for _, tag := range occurrences {
+				clone.duplicates[ifd][key] = append(clone.duplicates[ifd][key], cloneOnce(tag))
+			}
+		}
+	}
+	return clone
+}
+
+// TagsEqual reports whether a and b hold the same tag: same id, label and text
+// value, and the same typed value for integer, string and rational tags.
+func TagsEqual(a, b Tag) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Tag() != b.Tag() || a.TextLabel() != b.TextLabel() || a.TextValue() != b.TextValue() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case IntegerTag:
+		b, ok := b.(IntegerTag)
+		return ok && a.IntValue() == b.IntValue() && intsEqual(a.IntValues(), b.IntValues())
+	case StringTag:
+		b, ok := b.(StringTag)
+		return ok && stringsEqual(a.StringValues(), b.StringValues())
+	case FloatTag:
+		b, ok := b.(FloatTag)
+		return ok && a.Numerator() == b.Numerator() && a.Denominator() == b.Denominator() &&
+			floatsEqual(a.FloatValues(), b.FloatValues())
+	}
+	_, isInt := b.(IntegerTag)
+	_, isString := b.(StringTag)
+	_, isFloat := b.(FloatTag)
+	if isInt || isString || isFloat {
+		return false
+	}
+	aData, _ := a.Value().([]byte)
+	bData, _ := b.Value().([]byte)
+	return bytes.Equal(aData, bData)
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func intsEqual(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func floatsEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func cloneTag(tag Tag) Tag {
+	switch t := tag.(type) {
+	case *basicTag:
+		c := *t
+		c.data = append([]byte(nil), t.data...)
+		return &c
+	case *integerTag:
+		c := *t
+		c.intValues = append([]int(nil), t.intValues...)
+		return &c
+	case *stringTag:
+		c := *t
+		c.values = append([]string(nil), t.values...)
+		return &c
+	case *floatTag:
+		c := *t
+		c.components = append([]rationalComponent(nil), t.components...)
+		return &c
+	}
+	return tag
+}
+
+// SetTagNamer registers a function that names the tags libexif has no label
+// for, such as proprietary tags. While a namer is set, tags unknown to libexif
+// are kept instead of dropped and the data is not fixed up to the EXIF
+// specification. Pass nil to restore the default behavior.
+func (d *Data) SetTagNamer(f func(ifd, tag int) (name string, ok bool)) {
+	d.tagNamer = f
+}
+
+// Has reports whether the given tag is present.
+func (d *Data) Has(tag int) bool {
+	_, ok := d.Tags[tag]
+	return ok
+}
+
+// HasInIFD reports whether the given tag is present in the given IFD.
+func (d *Data) HasInIFD(ifd, tag int) bool {
+	if ifd < 0 || ifd >= ifdCount {
+		return false
+	}
+	_, ok := d.ifdTags[ifd][tag]
+	return ok
+}
+
+// TagsInIFD returns the tags read from the given IFD, one of IFD0, IFD1,
+// IFDExif, IFDGPS or IFDInteroperability. Unlike Tags, it holds every tag of
+// that IFD even when the same tag id also appears in another IFD.
+func (d *Data) TagsInIFD(ifd int) map[int]Tag {
+	tags := make(map[int]Tag)
+	if ifd < 0 || ifd >= ifdCount {
+		return tags
+	}
+	for key, tag := range d.ifdTags[ifd] {
+		tags[key] = tag
+	}
+	return tags
+}
+
+// ThumbnailSize returns the ImageWidth and ImageLength of the thumbnail, read
+// from IFD1 so they are never mistaken for the main image's.
+func (d *Data) ThumbnailSize() (width, height int, ok bool) {
+	w, ok := d.ifdTags[IFD1][TagImageWidth].(IntegerTag)
+	if !ok {
+		return 0, 0, false
+	}
+	h, ok := d.ifdTags[IFD1][TagImageLength].(IntegerTag)
+	if !ok {
+		return 0, 0, false
+	}
+	return w.IntValue(), h.IntValue(), true
+}
+
+// storeTag adds a parsed tag to both the flat and the per-IFD tag maps.
+func (d *Data) storeTag(tag Tag) {
+	d.Tags[tag.Tag()] = tag
+	if ifd := tag.Ifd(); ifd >= 0 && ifd < ifdCount {
+		if d.ifdTags[ifd] == nil {
+			d.ifdTags[ifd] = make(map[int]Tag)
+		}
+		d.ifdTags[ifd][tag.Tag()] = tag
+	}
+}
+
+// Each calls fn for every parsed tag, in the order libexif emitted them, until
+// fn returns false. Unlike Tags, it visits the tags of every IFD, including the
+// ones sharing an ID with a tag of another IFD.
+func (d *Data) Each(fn func(Tag) bool) {
+	for _, tag := range d.order {
+		if !fn(tag) {
+			return
+		}
+	}
+}
+
+// RawEntries returns every entry libexif read, in the order it emits them,
+// with its data bytes left uninterpreted. It is meant for tags the typed API
+// does not understand. The entries are copied out of the data kept with
+// WithKeepRaw on each call; without it, RawEntries returns nil.
+func (d *Data) RawEntries() []RawEntry {
+	if d.exifData == nil {
+		return nil
+	}
+	values := C.exif_dump(d.exifData)
+	defer C.free(unsafe.Pointer(values))
+
+	C.reverse_exif_stack(values)
+	var entries []RawEntry
+	for value := C.pop_exif_value(values); value != nil; value = C.pop_exif_value(values) {
+		entries = append(entries, newRawEntry(value))
+		C.free_exif_value(value)
+	}
+	return entries
+}
+
+// MakerNotes returns the entries of the maker note, by name, when libexif
+// recognizes its format, as it does for Canon, Fujifilm, Nikon, Olympus and
+// Pentax cameras among others. Unrecognized maker notes are still available
+// as bytes through the TagMakerNote entry of RawEntries.
+func (d *Data) MakerNotes() (map[string]string, bool) {
+	if !d.hasNotes {
+		return nil, false
+	}
+	notes := make(map[string]string, len(d.makerNotes))
+	for name, value := range d.makerNotes {
+		notes[name] = value
+	}
+	return notes, true
+}
+
+// UnhandledFormats returns the formats of the tags that were read as a plain
+// Tag, with a text value only, because their format has no typed
+// representation. It maps tag ids to EXIF format codes.
+func (d *Data) UnhandledFormats() map[int]int {
+	formats := make(map[int]int, len(d.unhandled))
+	for tag, format := range d.unhandled {
+		formats[tag] = format
+	}
+	return formats
+}
+
+// textValue returns the text value of the given tag, or an empty string if
+// the tag is not present.
+func (d *Data) textValue(tag int) string {
+	if t, ok := d.Tags[tag]; ok {
+		return t.TextValue()
+	}
+	return ""
 }
 
 func (d *Data) parseExifData(exifData *C.ExifData) error {
@@ -195,8 +1331,22 @@ func (d *Data) parseExifData(exifData *C.ExifData) error {
 
 	var byteOrder C.ExifByteOrder
 	var haveByteOrder bool
+	if d.byteOrder != nil {
+		byteOrder = C.EXIF_BYTE_ORDER_INTEL
+		if tiffHeader(d.byteOrder)[0] == 'M' {
+			byteOrder = C.EXIF_BYTE_ORDER_MOTOROLA
+		}
+		haveByteOrder = true
+	}
 
-	for {
+	// Values are pushed onto a stack, reverse it to pop them in the order
+	// libexif emitted them.
+	C.reverse_exif_stack(values)
+	var parsed []Tag
+	var stopped bool
+	var err error
+
+	for !stopped {
 		value := C.pop_exif_value(values)
 		if value == nil {
 			break
@@ -206,78 +1356,392 @@ func (d *Data) parseExifData(exifData *C.ExifData) error {
 				haveByteOrder = true
 			}
 			tagId := int(C.int((*value).rawValue.tag))
+			if d.want != nil && !d.want[tagId] {
+				C.free_exif_value(value)
+				continue
+			}
 			tagFmt := C.int((*value).rawValue.format)
-			var thisTag Tag
-			if tagFmt == exifFormatByte {
-				intTag := &integerTag{}
-				thisTag = intTag
-				intTag.intValue = int((*(*value).rawValue.data))
-			} else if tagFmt == exifFormatShort {
-				intTag := &integerTag{}
-				thisTag = intTag
-				intTag.intValue = int(C.exif_get_short((*value).rawValue.data, byteOrder))
-			} else if tagFmt == exifFormatLong {
-				intTag := &integerTag{}
-				thisTag = intTag
-				intTag.intValue = int(C.exif_get_long((*value).rawValue.data, byteOrder))
-			} else if tagFmt == exifFormatFloat {
-				intTag := &floatTag{}
-				thisTag = intTag
-				rational := C.exif_get_rational((*value).rawValue.data, byteOrder)
-				intTag.numerator = int(rational.numerator)
-				intTag.denominator = int(rational.denominator)
-				numComponents := int((*value).rawValue.components)
-				if numComponents > 1 {
-					for i := 1; i < numComponents; i++ {
-						rational = C.exif_get_rational_offset((*value).rawValue.data, byteOrder, C.int(i))
-						intTag.numerator = 60*intTag.numerator*int(rational.denominator) + int(rational.numerator)*intTag.denominator
-						intTag.denominator = intTag.denominator * int(rational.denominator) * 60
-					}
+			if d.strict {
+				err = checkFormat(int((*value).ifd), tagId, int(tagFmt))
+				if err == nil && !hasData(value) {
+					err = ErrMalformedExif
 				}
-			} else {
+				if err != nil {
+					C.free_exif_value(value)
+					break
+				}
+			}
+			var thisTag Tag
+			if !hasData(value) {
+				// Corrupt entries may come without any data to read.
 				thisTag = &basicTag{}
+			} else if _, ok := integerSizes[int(tagFmt)]; ok {
+				thisTag = newIntegerTag(entryBytes(value), int(tagFmt), goByteOrder(byteOrder))
+			} else if tagFmt == exifFormatString {
+				strTag := &stringTag{}
+				thisTag = strTag
+				strTag.values = splitStrings(entryBytes(value), !d.keepSpaces)
+			} else if tagFmt == exifFormatFloat || tagFmt == exifFormatSRational {
+				thisTag = newRationalTag(value, byteOrder, tagFmt == exifFormatSRational)
+			} else {
+				basic := &basicTag{}
+				if tagFmt == exifFormatUndefined {
+					basic.data = append([]byte(nil), entryBytes(value)...)
+				}
+				thisTag = basic
+				if d.unhandled == nil {
+					d.unhandled = make(map[int]int)
+				}
+				d.unhandled[tagId] = int(tagFmt)
 			}
 			thisTag.setTag(tagId)
 			thisTag.setTextLabel(strings.Trim(C.GoString((*value).name), " "))
-			thisTag.setTextValue(strings.Trim(C.GoString((*value).value), " "))
-			d.Tags[thisTag.Tag()] = thisTag
+			if thisTag.TextLabel() == "" && d.tagNamer != nil {
+				if name, ok := d.tagNamer(int((*value).ifd), tagId); ok {
+					thisTag.setTextLabel(name)
+				}
+			}
+			if d.keepSpaces {
+				thisTag.setTextValue(C.GoString((*value).value))
+			} else {
+				thisTag.setTextValue(strings.Trim(C.GoString((*value).value), " "))
+			}
+			if (*value).description != nil {
+				thisTag.setDescription(strings.Trim(C.GoString((*value).description), " "))
+			}
+			thisTag.setIfd(int((*value).ifd))
+			parsed = append(parsed, thisTag)
+			stopped = d.stop != nil && d.stop(thisTag)
 		}
 		C.free_exif_value(value)
 	}
+	for value := C.pop_exif_value(values); value != nil; value = C.pop_exif_value(values) {
+		C.free_exif_value(value)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Store the tags last to first, so that in Tags those of IFD0 take
+	// precedence over those of IFD1, and GPS over Interoperability.
+	for i := len(parsed) - 1; i >= 0; i-- {
+		d.storeTag(parsed[i])
+	}
+	d.order = append(d.order, parsed...)
+	if !stopped && (d.want == nil || d.want[TagMakerNote]) {
+		if d.makerNotes == nil {
+			d.makerNotes = make(map[string]string)
+		}
+		if readMakerNotes(exifData, d.makerNotes) {
+			d.hasNotes = true
+		}
+	}
 
 	return nil
 }
 
-// Write writes bytes to the exif loader. Sends ErrFoundExifInData error when
-// enough bytes have been sent.
+// hasData reports whether the entry behind value has at least one component
+// of data to read.
+func hasData(value *C.exif_value_t) bool {
+	entry := (*value).rawValue
+	return entry.data != nil && entry.components > 0 && entry.size >= C.uint(C.exif_format_get_size(entry.format))
+}
+
+// readMakerNotes stores the names and values of the maker note entries in
+// notes, or returns false if libexif does not recognize the maker note.
+func readMakerNotes(exifData *C.ExifData, notes map[string]string) bool {
+	md := C.exif_data_get_mnote_data(exifData)
+	if md == nil {
+		return false
+	}
+
+	buf := (*C.char)(C.malloc(makerNoteValueSize))
+	if buf == nil {
+		return false
+	}
+	defer C.free(unsafe.Pointer(buf))
+
+	count := int(C.exif_mnote_data_count(md))
+	for i := 0; i < count; i++ {
+		name := C.exif_mnote_data_get_name(md, C.uint(i))
+		if name == nil {
+			continue
+		}
+		value := C.exif_mnote_data_get_value(md, C.uint(i), buf, makerNoteValueSize)
+		if value == nil {
+			continue
+		}
+		notes[C.GoString(name)] = strings.Trim(C.GoString(value), " ")
+	}
+	return true
+}
+
+// entryBytes returns the data of the entry behind value without copying it.
+// The slice is only valid until value is freed.
+func entryBytes(value *C.exif_value_t) []byte {
+	if (*value).rawValue.data == nil {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer((*value).rawValue.data)), int((*value).rawValue.size))
+}
+
+// newRawEntry copies the entry behind value as it is stored in the file.
+func newRawEntry(value *C.exif_value_t) RawEntry {
+	entry := RawEntry{
+		Ifd:        int((*value).ifd),
+		Tag:        int((*value).rawValue.tag),
+		Format:     int((*value).rawValue.format),
+		Components: int((*value).rawValue.components),
+	}
+	if (*value).rawValue.data != nil && (*value).rawValue.size > 0 {
+		entry.Data = C.GoBytes(unsafe.Pointer((*value).rawValue.data), C.int((*value).rawValue.size))
+	}
+	return entry
+}
+
+// integerSizes gives the size of a component of each integer format.
+var integerSizes = map[int]int{
+	exifFormatByte:   1,
+	exifFormatSByte:  1,
+	exifFormatShort:  2,
+	exifFormatSShort: 2,
+	exifFormatLong:   4,
+	exifFormatSLong:  4,
+}
+
+// newIntegerTag reads the components of an entry of one of the integer
+// formats from its raw data. Those of the signed formats are sign extended.
+func newIntegerTag(data []byte, format int, order binary.ByteOrder) *integerTag {
+	size := integerSizes[format]
+	values := make([]int, len(data)/size)
+	for i := range values {
+		p := data[i*size:]
+		switch format {
+		case exifFormatByte:
+			values[i] = int(p[0])
+		case exifFormatSByte:
+			values[i] = int(int8(p[0]))
+		case exifFormatShort:
+			values[i] = int(order.Uint16(p))
+		case exifFormatSShort:
+			values[i] = int(int16(order.Uint16(p)))
+		case exifFormatLong:
+			values[i] = int(order.Uint32(p))
+		case exifFormatSLong:
+			values[i] = int(int32(order.Uint32(p)))
+		}
+	}
+	return &integerTag{intValue: values[0], intValues: values}
+}
+
+// goByteOrder returns the binary.ByteOrder of a libexif byte order.
+func goByteOrder(order C.ExifByteOrder) binary.ByteOrder {
+	if order == C.EXIF_BYTE_ORDER_MOTOROLA {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// newRationalTag reads the components of a RATIONAL or SRATIONAL entry. The
+// sign of each component is carried by its numerator. Entries with a zero
+// denominator have no meaningful value and are returned as a basicTag.
+func newRationalTag(value *C.exif_value_t, byteOrder C.ExifByteOrder, signed bool) Tag {
+	components := make([]rationalComponent, int((*value).rawValue.components))
+	if len(components) == 0 {
+		return &basicTag{}
+	}
+	for i := range components {
+		if signed {
+			component := C.exif_get_srational_offset((*value).rawValue.data, byteOrder, C.int(i))
+			components[i] = newRationalComponent(int(component.numerator), int(component.denominator))
+		} else {
+			component := C.exif_get_rational_offset((*value).rawValue.data, byteOrder, C.int(i))
+			components[i] = newRationalComponent(int(component.numerator), int(component.denominator))
+		}
+		if components[i].denominator == 0 {
+			return &basicTag{}
+		}
+	}
+
+	tag := &floatTag{components: components}
+	tag.numerator = components[0].numerator
+	tag.denominator = components[0].denominator
+	return tag
+}
+
+func newRationalComponent(numerator, denominator int) rationalComponent {
+	if denominator < 0 {
+		numerator, denominator = -numerator, -denominator
+	}
+	return rationalComponent{numerator, denominator}
+}
+
+// splitStrings splits the raw value of an ASCII tag into its NUL-terminated
+// strings, trimming the spaces around each of them if trim is set.
+func splitStrings(raw []byte, trim bool) []string {
+	var values []string
+	for _, piece := range strings.Split(string(raw), "\x00") {
+		if trim {
+			piece = strings.Trim(piece, " ")
+		}
+		if piece != "" {
+			values = append(values, piece)
+		}
+	}
+	return values
+}
+
+// Write writes bytes to the exif loader. Done reports when the loader needs
+// no more bytes, at which point Parse can be called; bytes written after that
+// are accepted without an error but ignored, so that Data can sit behind
+// io.Copy or io.MultiWriter. The loader is also done when it gives up on data
+// that holds no EXIF; Parse then reports ErrNoExifData. If a limit was set
+// with WithMaxBytes, ErrMaxBytesExceeded is returned once the limit is
+// reached without the loader being done.
 func (d *Data) Write(p []byte) (n int, err error) {
 	if d.exifLoader == nil {
-		d.exifLoader = C.exif_loader_new()
-		runtime.SetFinalizer(d, (*Data).cleanup)
+		d.exifLoader = newLoader()
+		d.setFinalizer()
+		d.resetXMP()
+	}
+
+	if d.loaderDone {
+		// Only the search for the XMP packet may still want the bytes.
+		d.scanXMP(p)
+		return len(p), nil
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if d.maxBytes > 0 {
+		remaining := d.maxBytes - d.written
+		if remaining <= 0 {
+			return 0, ErrMaxBytesExceeded
+		}
+		if len(p) > remaining {
+			p = p[:remaining]
+		}
 	}
 
 	res := C.exif_loader_write(d.exifLoader, (*C.uchar)(unsafe.Pointer(&p[0])), C.uint(len(p)))
+	d.written += len(p)
+	d.scanXMP(p)
 
-	if res == 1 {
+	if res != 1 {
+		d.loaderDone = true
 		return len(p), nil
 	}
-	return len(p), ErrFoundExifInData
+	if d.maxBytes > 0 && d.written >= d.maxBytes {
+		return len(p), ErrMaxBytesExceeded
+	}
+	return len(p), nil
+}
+
+// Done reports whether the exif loader written to by Write needs no more
+// bytes, that is, whether Parse can be called. It is reset by Parse.
+func (d *Data) Done() bool {
+	return d.loaderDone
+}
+
+// ReadFrom writes data from r to the exif loader until the loader has all of
+// the EXIF data or r is exhausted, and returns the number of bytes read. Call
+// Parse afterwards to set the tags.
+func (d *Data) ReadFrom(r io.Reader) (int64, error) {
+	var total int64
+	buf := make([]byte, readChunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			total += int64(n)
+			if _, werr := d.Write(buf[:n]); werr != nil {
+				return total, werr
+			}
+			if d.loaderDone {
+				return total, nil
+			}
+		}
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// WriteTo writes the parsed EXIF data to w as a standalone TIFF structure,
+// as libexif saves it, and returns the number of bytes written. The result
+// can be embedded into another container, for instance after the "Exif\0\0"
+// header of a JPEG APP1 segment. It needs the data kept with WithKeepRaw and
+// returns ErrNoExifData without it.
+func (d *Data) WriteTo(w io.Writer) (int64, error) {
+	if !libexifAvailable() {
+		return 0, ErrLibexifUnavailable
+	}
+	if d.exifData == nil {
+		return 0, ErrNoExifData
+	}
+
+	var buf *C.uchar
+	var size C.uint
+	C.exif_data_save_data(d.exifData, &buf, &size)
+	if buf == nil {
+		return 0, ErrEncodeExif
+	}
+	defer C.free(unsafe.Pointer(buf))
+
+	// libexif starts the data with the header of the APP1 segment.
+	tiff := bytes.TrimPrefix(C.GoBytes(unsafe.Pointer(buf), C.int(size)), exifHeader)
+	if _, ok := tiffByteOrder(tiff); !ok {
+		return 0, ErrEncodeExif
+	}
+	n, err := w.Write(tiff)
+	return int64(n), err
 }
 
 // Parse finalizes the data loader and sets the tags
 func (d *Data) Parse() error {
 	defer d.cleanup()
 
-	exifData := C.exif_loader_get_data(d.exifLoader)
-	if exifData == nil {
-		return ErrNoExifData
+	return d.parseLoader(d.exifLoader, d.loaderDone)
+}
+
+// Thumbnail returns a copy of the JPEG thumbnail of IFD1. It is only available
+// while the parsed data is kept with WithKeepRaw.
+func (d *Data) Thumbnail() ([]byte, bool) {
+	if d.exifData == nil || d.exifData.data == nil || d.exifData.size == 0 {
+		return nil, false
 	}
+	return C.GoBytes(unsafe.Pointer(d.exifData.data), C.int(d.exifData.size)), true
+}
 
-	defer func() {
-		C.exif_data_unref(exifData)
-	}()
+// Close releases the parsed data kept with WithKeepRaw, the exif loader of an
+// unfinished Write and the one a Parser keeps. It is safe to call Close more
+// than once.
+func (d *Data) Close() error {
+	d.releaseExifData()
+	d.cleanup()
+	if d.readLoader != nil {
+		C.exif_loader_unref(d.readLoader)
+		d.readLoader = nil
+	}
+	return nil
+}
 
-	return d.parseExifData(exifData)
+// setFinalizer makes the garbage collector release what Close releases. A
+// finalizer set by an earlier call is replaced, since setting one twice
+// panics.
+func (d *Data) setFinalizer() {
+	runtime.SetFinalizer(d, nil)
+	runtime.SetFinalizer(d, (*Data).Close)
+}
+
+func (d *Data) releaseExifData() {
+	if d.exifData != nil {
+		C.exif_data_unref(d.exifData)
+		d.exifData = nil
+	}
 }
 
 func (d *Data) cleanup() {
@@ -285,4 +1749,7 @@ func (d *Data) cleanup() {
 		C.exif_loader_unref(d.exifLoader)
 		d.exifLoader = nil
 	}
+	d.loaderDone = false
+	d.written = 0
+	d.finishXMP()
 }
@@ -26,31 +26,49 @@
 #include <string.h>
 
 #include <libexif/exif-data.h>
+#include <libexif/exif-log.h>
 #include <libexif/exif-utils.h>
 
 #include "_cgo/types.h"
 
 #define EXIF_VALUE_MAXLEN 256
+#define EXIF_LOG_MAXLEN 1024
+
+extern void goExifLog(int code, char* domain, char* msg);
 
 void import_entry(ExifEntry*, void*);
 void import_ifds(ExifContent*, void*);
 exif_value_t *new_exif_value(void);
 void push_exif_value(exif_stack_t*, exif_value_t*);
 exif_value_t* pop_exif_value(exif_stack_t *);
+void reverse_exif_stack(exif_stack_t *);
 void free_exif_value(exif_value_t* n);
 exif_stack_t* exif_dump(ExifData *);
+ExifLog* new_exif_log(void);
 
 void import_entry(ExifEntry* entry, void* user_data) {
   exif_value_t* value;
+  const char* title;
   char exif_text[EXIF_VALUE_MAXLEN];
 
   value = new_exif_value();
 
+  if (value == NULL) {
+    return;
+  }
+
   ExifIfd ifd = exif_entry_get_ifd(entry);
 
   value->rawValue = entry;
-  strncpy(value->name, exif_tag_get_title_in_ifd(entry->tag, ifd), EXIF_VALUE_MAXLEN);
-  strncpy(value->value, exif_entry_get_value(entry, exif_text, EXIF_VALUE_MAXLEN), EXIF_VALUE_MAXLEN);
+  value->ifd = ifd;
+
+  /* libexif has no title for tags it does not know in this IFD. */
+  title = exif_tag_get_title_in_ifd(entry->tag, ifd);
+  if (title != NULL) {
+    strncpy(value->name, title, EXIF_VALUE_MAXLEN - 1);
+  }
+  strncpy(value->value, exif_entry_get_value(entry, exif_text, EXIF_VALUE_MAXLEN), EXIF_VALUE_MAXLEN - 1);
+  value->description = exif_tag_get_description_in_ifd(entry->tag, ifd);
 
   push_exif_value(user_data, value);
 }
@@ -67,12 +85,17 @@ exif_value_t* new_exif_value() {
     return NULL;
   }
 
-  n->name = (char *)malloc(sizeof(char)*EXIF_VALUE_MAXLEN);
-  n->value = (char *)malloc(sizeof(char)*EXIF_VALUE_MAXLEN);
+  n->name = (char *)calloc(EXIF_VALUE_MAXLEN, sizeof(char));
+  n->value = (char *)calloc(EXIF_VALUE_MAXLEN, sizeof(char));
 
+  if (n->name == NULL || n->value == NULL) {
+    free_exif_value(n);
+    return NULL;
+  }
+
+  n->description = NULL;
+  n->ifd = EXIF_IFD_COUNT;
   n->rawValue = '\0';
-  n->name[0]  = '\0';
-  n->value[0] = '\0';
   n->prev     = 0;
   return n;
 }
@@ -83,6 +106,12 @@ exif_get_rational_offset (const unsigned char *buf, ExifByteOrder order, int off
     return exif_get_rational(buf+8*offset, order);
 }
 
+ExifSRational
+exif_get_srational_offset (const unsigned char *buf, ExifByteOrder order, int offset)
+{
+    return exif_get_srational(buf+8*offset, order);
+}
+
 
 void push_exif_value(exif_stack_t* stack, exif_value_t* n) {
   n->prev = stack->head;
@@ -91,7 +120,7 @@ void push_exif_value(exif_stack_t* stack, exif_value_t* n) {
 
 exif_value_t* pop_exif_value(exif_stack_t *stack) {
   exif_value_t *n;
-  if (stack->head == NULL) {
+  if (stack == NULL || stack->head == NULL) {
     return NULL;
   }
   n = stack->head;
@@ -99,6 +128,25 @@ exif_value_t* pop_exif_value(exif_stack_t *stack) {
   return n;
 }
 
+/* Reverses the stack, so that values pop in the order they were pushed. */
+void reverse_exif_stack(exif_stack_t *stack) {
+  exif_value_t *n, *next, *reversed;
+  if (stack == NULL) {
+    return;
+  }
+  reversed = NULL;
+  n = stack->head;
+  while (n != NULL) {
+    next = n->prev;
+    n->prev = reversed;
+    reversed = n;
+    n = next;
+  }
+  stack->head = reversed;
+}
+
+/* Releases a node along with its name and value buffers. The description
+   points into libexif's static tag table and is not owned by the node. */
 void free_exif_value(exif_value_t* n) {
   free(n->name);
   free(n->value);
@@ -109,9 +157,31 @@ exif_stack_t* exif_dump(ExifData* data) {
   exif_stack_t* user_data;
 
   user_data = (exif_stack_t*)malloc(sizeof(exif_stack_t));
+  if (user_data == NULL) {
+    return NULL;
+  }
   user_data->head = NULL;
 
   exif_data_foreach_content(data, import_ifds, user_data);
 
   return user_data;
 }
+
+static void log_func(ExifLog* log, ExifLogCode code, const char* domain, const char* format, va_list args, void* data) {
+  char msg[EXIF_LOG_MAXLEN];
+
+  vsnprintf(msg, EXIF_LOG_MAXLEN, format, args);
+  goExifLog(code, (char *)domain, msg);
+}
+
+/* Creates a log that forwards every message to goExifLog. */
+ExifLog* new_exif_log() {
+  ExifLog* log;
+
+  log = exif_log_new();
+  if (log != NULL) {
+    exif_log_set_func(log, log_func, NULL);
+  }
+
+  return log;
+}
@@ -1,13 +1,132 @@
 package exif
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 )
 
+// testEntry is a raw IFD entry used to craft EXIF data for tests.
+type testEntry struct {
+	tag    int
+	format int
+	count  int
+	data   []byte
+}
+
+func shortEntry(tag int, values ...int) testEntry {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(data[2*i:], uint16(v))
+	}
+	return testEntry{tag, exifFormatShort, len(values), data}
+}
+
+func longEntry(tag int, values ...int) testEntry {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(data[4*i:], uint32(v))
+	}
+	return testEntry{tag, exifFormatLong, len(values), data}
+}
+
+// rationalEntry takes numerator and denominator pairs.
+func rationalEntry(tag int, format int, values ...int) testEntry {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(data[4*i:], uint32(int32(v)))
+	}
+	return testEntry{tag, format, len(values) / 2, data}
+}
+
+func asciiEntry(tag int, value string) testEntry {
+	return testEntry{tag, exifFormatString, len(value) + 1, append([]byte(value), 0)}
+}
+
+// encodeIFD encodes a big-endian IFD that starts at the given offset,
+// followed by the values that do not fit in their entries.
+func encodeIFD(entries []testEntry, offset int) []byte {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].tag < entries[j].tag
+	})
+	out := make([]byte, 2+12*len(entries)+4)
+	binary.BigEndian.PutUint16(out, uint16(len(entries)))
+	for i, e := range entries {
+		p := out[2+12*i:]
+		binary.BigEndian.PutUint16(p, uint16(e.tag))
+		binary.BigEndian.PutUint16(p[2:], uint16(e.format))
+		binary.BigEndian.PutUint32(p[4:], uint32(e.count))
+		if len(e.data) <= 4 {
+			copy(p[8:12], e.data)
+			continue
+		}
+		binary.BigEndian.PutUint32(p[8:], uint32(offset+len(out)))
+		out = append(out, e.data...)
+		if len(out)%2 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+// buildTIFF encodes a big-endian TIFF structure with the given IFD0 entries
+// and, when not nil, Exif and GPS sub-IFDs.
+func buildTIFF(ifd0, exifIFD, gpsIFD []testEntry) []byte {
+	pointers := func(exifOffset, gpsOffset int) []testEntry {
+		entries := append([]testEntry(nil), ifd0...)
+		if exifIFD != nil {
+			entries = append(entries, longEntry(0x8769, exifOffset))
+		}
+		if gpsIFD != nil {
+			entries = append(entries, longEntry(0x8825, gpsOffset))
+		}
+		return entries
+	}
+
+	exifOffset := 8 + len(encodeIFD(pointers(0, 0), 8))
+	exifBytes := encodeIFD(exifIFD, exifOffset)
+	gpsOffset := exifOffset
+	if exifIFD != nil {
+		gpsOffset += len(exifBytes)
+	}
+
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = append(tiff, encodeIFD(pointers(exifOffset, gpsOffset), 8)...)
+	if exifIFD != nil {
+		tiff = append(tiff, exifBytes...)
+	}
+	if gpsIFD != nil {
+		tiff = append(tiff, encodeIFD(gpsIFD, gpsOffset)...)
+	}
+	return tiff
+}
+
+// withIFD1 appends a thumbnail IFD with the given entries to a TIFF structure
+// made by buildTIFF.
+func withIFD1(tiff []byte, entries []testEntry) []byte {
+	offset := len(tiff)
+	next := 8 + 2 + 12*int(binary.BigEndian.Uint16(tiff[8:]))
+	tiff = append([]byte(nil), tiff...)
+	binary.BigEndian.PutUint32(tiff[next:], uint32(offset))
+	return append(tiff, encodeIFD(entries, offset)...)
+}
+
+// buildJPEG wraps a TIFF structure in the EXIF segment of a minimal JPEG.
+func buildJPEG(t *testing.T, tiff []byte) []byte {
+	segment, err := exifSegmentBytes(tiff)
+	assert.NoError(t, err)
+	b := append([]byte{0xFF, jpegSOI}, segment...)
+	return append(b, 0xFF, jpegEOI)
+}
+
 func TestOpen(t *testing.T) {
 	exif := New()
 
@@ -33,6 +152,535 @@ func TestRead(t *testing.T) {
 	}
 }
 
+func TestHasExif(t *testing.T) {
+	ok, err := HasExif("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	b, err := os.ReadFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	stripped, err := StripExif(b)
+	assert.NoError(t, err)
+	assert.False(t, HasExifBytes(stripped))
+
+	path := filepath.Join(t.TempDir(), "stripped.jpg")
+	err = os.WriteFile(path, stripped, 0644)
+	assert.NoError(t, err)
+	ok, err = HasExif(path)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	tiff := buildTIFF([]testEntry{asciiEntry(TagMake, "Maker")}, nil, nil)
+	assert.True(t, HasExifBytes(buildJPEG(t, tiff)))
+	assert.True(t, HasExifBytes(buildPNG(tiff)))
+	assert.False(t, HasExifBytes(buildPNG(nil)))
+	assert.True(t, HasExifBytes(buildWebP(tiff)))
+	assert.True(t, HasExifBytes(buildHEIC(tiff, false)))
+	assert.False(t, HasExifBytes(buildHEIC(nil, false)))
+	assert.True(t, HasExifBytes(tiff))
+	assert.False(t, HasExifBytes([]byte("MM\x00\x2a\x00\x00\x00\x08\x00\x00")))
+	assert.False(t, HasExifBytes(nil))
+
+	// The chunks of a PNG file are walked on disk.
+	path = filepath.Join(t.TempDir(), "image.png")
+	err = os.WriteFile(path, buildPNG(tiff), 0644)
+	assert.NoError(t, err)
+	ok, err = HasExif(path)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = HasExif("_examples/resources/missing.jpg")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestReadContainers(t *testing.T) {
+	tiff := buildTIFF([]testEntry{asciiEntry(TagMake, "Maker")}, nil, nil)
+	withHeader := append(append([]byte(nil), exifHeader...), tiff...)
+
+	for _, test := range []struct {
+		name string
+		b    []byte
+	}{
+		{"image.tif", tiff},
+		{"image.png", buildPNG(tiff)},
+		{"image.webp", buildWebP(tiff)},
+		{"image.heic", buildHEIC(tiff, false)},
+		{"idat.heic", buildHEIC(tiff, true)},
+		// Some writers keep the JPEG style header in front of the TIFF
+		// structure.
+		{"header.png", buildPNG(withHeader)},
+		{"header.webp", buildWebP(withHeader)},
+	} {
+		exif, err := ReadBytes(test.b)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, "Maker", exif.Make(), test.name)
+
+		path := filepath.Join(t.TempDir(), test.name)
+		err = os.WriteFile(path, test.b, 0644)
+		assert.NoError(t, err)
+
+		exif, err = Read(path)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, "Maker", exif.Make(), test.name)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	err := New().Open("_examples/resources/missing.jpg")
+	var fileErr *FileError
+	assert.True(t, errors.As(err, &fileErr))
+	assert.Equal(t, "_examples/resources/missing.jpg", fileErr.Path)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	err = New().OpenContext(context.Background(), "_examples/resources/missing.jpg")
+	assert.True(t, errors.As(err, &fileErr))
+
+	// A readable file without EXIF data.
+	err = New().Open("README.md")
+	assert.Equal(t, ErrNoExifData, err)
+}
+
+func TestOpenTags(t *testing.T) {
+	exif := New()
+
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	err := exif.OpenTags("_examples/resources/test.jpg", []int{TagOrientation, TagMake})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(exif.Tags))
+	assert.True(t, exif.Has(TagOrientation))
+	assert.Equal(t, "FUJIFILM", exif.Make())
+}
+
+func TestOpenUntil(t *testing.T) {
+	exif := New()
+
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	err := exif.OpenUntil("_examples/resources/test.jpg", func(tag Tag) bool {
+		return tag.Tag() == TagOrientation
+	})
+	assert.NoError(t, err)
+	assert.True(t, exif.Has(TagOrientation))
+	assert.Equal(t, "FUJIFILM", exif.Make())
+	assert.False(t, exif.Has(TagDateTimeOriginal))
+
+	var last Tag
+	exif.Each(func(tag Tag) bool {
+		last = tag
+		return true
+	})
+	assert.Equal(t, TagOrientation, last.Tag())
+
+	// The stop function is only used for that call.
+	err = exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	assert.True(t, exif.Has(TagDateTimeOriginal))
+}
+
+func TestOpenContext(t *testing.T) {
+	exif := New()
+
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	err := exif.OpenContext(context.Background(), "_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "FUJIFILM", exif.Make())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = New().OpenContext(ctx, "_examples/resources/test.jpg")
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestReadBytes(t *testing.T) {
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	b, err := os.ReadFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	exif, err := ReadBytes(b)
+	assert.NoError(t, err)
+	assert.Equal(t, "FUJIFILM", exif.Make())
+
+	_, err = ReadBytes([]byte("not an image"))
+	assert.Equal(t, ErrNoExifData, err)
+}
+
+func TestReadFile(t *testing.T) {
+	exif := New()
+
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	file, err := os.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	err = exif.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "FUJIFILM", exif.Make())
+
+	// The caller's file must still be open.
+	_, err = file.Stat()
+	assert.NoError(t, err)
+}
+
+func TestHas(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	assert.True(t, exif.Has(TagOrientation))
+	assert.False(t, exif.Has(TagGPSDateStamp))
+}
+
+func TestTagsInIFD(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+
+	// GPSLatitudeRef and InteroperabilityIndex share tag id 1.
+	assert.True(t, exif.HasInIFD(IFDGPS, TagLatitudeRef))
+	assert.True(t, exif.HasInIFD(IFDInteroperability, TagLatitudeRef))
+	assert.False(t, exif.HasInIFD(IFD0, TagLatitudeRef))
+	assert.False(t, exif.HasInIFD(ifdCount, TagLatitudeRef))
+
+	gps := exif.TagsInIFD(IFDGPS)
+	assert.Equal(t, "S", gps[TagLatitudeRef].TextValue())
+	assert.Equal(t, IFDGPS, gps[TagLatitudeRef].Ifd())
+	assert.True(t, gps[TagGPSDateStamp] != nil)
+	assert.True(t, gps[TagMake] == nil)
+
+	interop := exif.TagsInIFD(IFDInteroperability)
+	assert.Equal(t, "R98", interop[TagLatitudeRef].TextValue())
+
+	assert.Equal(t, 0, len(exif.TagsInIFD(-1)))
+}
+
+func TestThumbnailIFD(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	// Orientation is recorded for both images, Compression for the thumbnail.
+	assert.True(t, exif.HasInIFD(IFD0, TagOrientation))
+	assert.True(t, exif.HasInIFD(IFD1, TagOrientation))
+	assert.Equal(t, IFD0, exif.Tags[TagOrientation].Ifd())
+	assert.Equal(t, "JPEG compression", exif.TagsInIFD(IFD1)[TagCompression].TextValue())
+	assert.Equal(t, IFD1, exif.TagsInIFD(IFD1)[TagOrientation].Ifd())
+
+	tiff := buildTIFF([]testEntry{longEntry(TagImageWidth, 4000), longEntry(TagImageLength, 3000)}, nil, nil)
+	tiff = withIFD1(tiff, []testEntry{shortEntry(TagImageWidth, 160), shortEntry(TagImageLength, 120)})
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 4000, exif.Tags[TagImageWidth].(IntegerTag).IntValue())
+	assert.Equal(t, 3000, exif.Tags[TagImageLength].(IntegerTag).IntValue())
+	width, height, ok := exif.ThumbnailSize()
+	assert.True(t, ok)
+	assert.Equal(t, 160, width)
+	assert.Equal(t, 120, height)
+
+	exif, err = ReadBytes(buildJPEG(t, buildTIFF([]testEntry{asciiEntry(TagMake, "Maker")}, nil, nil)))
+	assert.NoError(t, err)
+	_, _, ok = exif.ThumbnailSize()
+	assert.False(t, ok)
+}
+
+func TestEach(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+
+	var tags []Tag
+	exif.Each(func(tag Tag) bool {
+		tags = append(tags, tag)
+		return true
+	})
+	assert.True(t, len(tags) > len(exif.Tags))
+
+	// IFDs are emitted in turn, IFD0 first.
+	assert.Equal(t, IFD0, tags[0].Ifd())
+	for i := 1; i < len(tags); i++ {
+		assert.True(t, tags[i-1].Ifd() <= tags[i].Ifd())
+	}
+	for _, tag := range exif.Tags {
+		assert.Contains(t, tags, tag)
+	}
+
+	calls := 0
+	exif.Each(func(tag Tag) bool {
+		calls++
+		return false
+	})
+	assert.Equal(t, 1, calls)
+
+	// Parsing other data replaces the tags visited.
+	tiff := buildTIFF([]testEntry{asciiEntry(TagMake, "Maker"), asciiEntry(TagModel, "Model")}, nil, nil)
+	err = exif.ParseBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	var ids []int
+	exif.Each(func(tag Tag) bool {
+		ids = append(ids, tag.Tag())
+		return true
+	})
+	assert.Equal(t, []int{TagMake, TagModel}, ids[:2])
+}
+
+func TestRawEntries(t *testing.T) {
+	tiff := buildTIFF(
+		[]testEntry{asciiEntry(TagMake, "Maker")},
+		[]testEntry{
+			rationalEntry(TagExposureTime, exifFormatFloat, 1, 250),
+			shortEntry(TagISOSpeedRatings, 100, 200),
+		},
+		nil,
+	)
+	exif := New()
+	err := exif.ParseBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	assert.Nil(t, exif.RawEntries())
+
+	exif = New(WithKeepRaw())
+	defer exif.Close()
+	err = exif.ParseBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	entries := map[int]RawEntry{}
+	for _, entry := range exif.RawEntries() {
+		entries[entry.Tag] = entry
+	}
+
+	assert.Equal(t, RawEntry{IFD0, TagMake, exifFormatString, 6, []byte("Maker\x00")}, entries[TagMake])
+	assert.Equal(t, RawEntry{IFDExif, TagExposureTime, exifFormatFloat, 1, []byte{0, 0, 0, 1, 0, 0, 0, 250}}, entries[TagExposureTime])
+	assert.Equal(t, RawEntry{IFDExif, TagISOSpeedRatings, exifFormatShort, 2, []byte{0, 100, 0, 200}}, entries[TagISOSpeedRatings])
+
+	// The returned entries are copies.
+	exif.RawEntries()[0].Data[0] = 'X'
+	assert.Equal(t, byte('M'), exif.RawEntries()[0].Data[0])
+
+	// Parsing other data replaces the entries.
+	err = exif.ParseBytes(buildJPEG(t, buildTIFF([]testEntry{asciiEntry(TagModel, "Model")}, nil, nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, []RawEntry{{IFD0, TagModel, exifFormatString, 6, []byte("Model\x00")}}, exif.RawEntries())
+
+	// So does parsing data without any.
+	err = exif.ParseBytes(buildPNG(nil))
+	assert.Equal(t, ErrNoExifData, err)
+	assert.Equal(t, 0, len(exif.RawEntries()))
+}
+
+func TestUnhandledFormats(t *testing.T) {
+	const tagExifVersion = 0x9000
+	tiff := buildTIFF(nil, []testEntry{
+		{tagExifVersion, exifFormatUndefined, 4, []byte("0230")},
+		shortEntry(TagISOSpeedRatings, 100),
+	}, nil)
+
+	exif, err := ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	formats := exif.UnhandledFormats()
+	assert.Equal(t, exifFormatUndefined, formats[tagExifVersion])
+	_, ok := formats[TagISOSpeedRatings]
+	assert.False(t, ok)
+
+	// The returned map is a copy.
+	delete(formats, tagExifVersion)
+	assert.Equal(t, exifFormatUndefined, exif.UnhandledFormats()[tagExifVersion])
+}
+
+func TestMakerNotes(t *testing.T) {
+	// A Fujifilm maker note, always little-endian, with its offsets relative
+	// to the start of the note.
+	note := []byte("FUJIFILM\x0c\x00\x00\x00")
+	note = append(note, 1, 0)
+	note = append(note, 0x00, 0x10, 2, 0, 5, 0, 0, 0, 30, 0, 0, 0)
+	note = append(note, 0, 0, 0, 0)
+	note = append(note, "FINE\x00"...)
+
+	tiff := buildTIFF(
+		[]testEntry{asciiEntry(TagMake, "FUJIFILM")},
+		[]testEntry{{TagMakerNote, 7, len(note), note}},
+		nil,
+	)
+	exif, err := ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	notes, ok := exif.MakerNotes()
+	assert.True(t, ok)
+	assert.Equal(t, "FINE", notes["Quality"])
+
+	exif = New()
+	err = exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	_, ok = exif.MakerNotes()
+	assert.False(t, ok)
+}
+
+func TestSetTagNamer(t *testing.T) {
+	const privateTag = 0xBEEF
+	tiff := buildTIFF([]testEntry{asciiEntry(privateTag, "secret")}, nil, nil)
+
+	exif := New()
+	err := exif.ParseBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	assert.False(t, exif.Has(privateTag))
+
+	exif = New()
+	exif.SetTagNamer(func(ifd, tag int) (string, bool) {
+		if ifd == IFD0 && tag == privateTag {
+			return "Private", true
+		}
+		return "", false
+	})
+	err = exif.ParseBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	assert.True(t, exif.Has(privateTag))
+	assert.Equal(t, "Private", exif.Tags[privateTag].TextLabel())
+}
+
+func TestDescription(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, "", exif.Tags[TagMake].Description())
+}
+
+func TestStringValues(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	cameraMake, ok := exif.Tags[TagMake].(StringTag)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"FUJIFILM"}, cameraMake.StringValues())
+
+	assert.Equal(t, []string{"ASCII", "FUSED"}, splitStrings([]byte("ASCII\x00\x00\x00FUSED\x00"), true))
+	assert.Equal(t, []string(nil), splitStrings([]byte("  \x00"), true))
+	assert.Equal(t, []string{" a "}, splitStrings([]byte(" a \x00"), false))
+}
+
+func TestUntrimmedValues(t *testing.T) {
+	const tagImageDescription = 270
+	tiff := buildTIFF([]testEntry{asciiEntry(tagImageDescription, "  Caption  ")}, nil, nil)
+
+	exif, err := ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	assert.Equal(t, "Caption", exif.Tags[tagImageDescription].TextValue())
+
+	exif = New(WithUntrimmedValues())
+	err = exif.ParseBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	assert.Equal(t, "  Caption  ", exif.Tags[tagImageDescription].TextValue())
+	assert.Equal(t, []string{"  Caption  "}, exif.Tags[tagImageDescription].(StringTag).StringValues())
+	assert.Equal(t, "Image Description", exif.Tags[tagImageDescription].TextLabel())
+}
+
+func TestParseEmptyExif(t *testing.T) {
+	// An EXIF segment whose TIFF header is unreadable is damaged, not
+	// truncated: it holds no tags.
+	b := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x10}
+	b = append(b, "Exif\x00\x00XX\x00\x00\x00\x00\x00\x00"...)
+	b = append(b, 0xFF, 0xD9)
+
+	exif := New()
+	err := exif.ParseBytes(b)
+	assert.Equal(t, ErrNoExifData, err)
+	assert.Equal(t, 0, len(exif.Tags))
+
+	// A valid TIFF header whose IFD0 has no entries: libexif loads the data
+	// but its dump is empty.
+	exif = New()
+	_, err = exif.Write(buildJPEG(t, buildTIFF(nil, nil, nil)))
+	assert.NoError(t, err)
+	assert.True(t, exif.Done())
+	err = exif.Parse()
+	assert.Equal(t, ErrNoExifData, err)
+	assert.Equal(t, 0, len(exif.Tags))
+	assert.Equal(t, 0, len(exif.RawEntries()))
+}
+
+func TestTruncatedExif(t *testing.T) {
+	for _, file := range []string{"_examples/resources/test.jpg", "_examples/resources/testlocation.jpg"} {
+		b, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		segments, _, err := jpegSegments(b)
+		assert.NoError(t, err)
+
+		var end int
+		for _, segment := range segments {
+			if segment.isExif() {
+				end = segment.end
+			}
+		}
+		assert.True(t, end > 0)
+
+		// Every cut through the EXIF segment must return an error, never
+		// crash.
+		for n := 0; n < end; n++ {
+			err := New().ParseBytes(b[:n])
+			assert.Error(t, err)
+		}
+		assert.NoError(t, New().ParseBytes(b[:end]))
+
+		// The tags read before the cut are returned with the error.
+		exif, err := ReadBytes(b[:end-1])
+		assert.Equal(t, ErrTruncatedExif, err)
+		assert.True(t, exif != nil && len(exif.Tags) > 0)
+	}
+
+	// IFD0 is cut in the middle of its second entry.
+	tiff := buildTIFF([]testEntry{shortEntry(TagOrientation, 6), shortEntry(TagSamplesPerPixel, 3)}, nil, nil)
+	exif, err := ReadBytes(tiff[:8+2+12+6])
+	assert.Equal(t, ErrTruncatedExif, err)
+	assert.True(t, exif != nil)
+	assert.Equal(t, 6, exif.Tags[TagOrientation].(IntegerTag).IntValue())
+}
+
+func TestDamagedExif(t *testing.T) {
+	// A MakerNote, a thumbnail and a GPS IFD pointing past the end of the
+	// data are skipped; the rest is read without an error.
+	tiff := buildTIFF(
+		[]testEntry{asciiEntry(TagMake, "Maker"), longEntry(0x8825, 0x7FFFFF00)},
+		[]testEntry{{0x927c, exifFormatUndefined, 100, []byte{0x7F, 0xFF, 0xFF, 0x00}}},
+		nil,
+	)
+	tiff = withIFD1(tiff, []testEntry{longEntry(tagJPEGInterchangeFormat, 0x7FFFFF00), longEntry(tagJPEGInterchangeFormatLength, 4096)})
+
+	for _, b := range [][]byte{tiff, buildJPEG(t, tiff)} {
+		exif, err := ReadBytes(b)
+		assert.NoError(t, err)
+		assert.Equal(t, "Maker", exif.Make())
+		assert.False(t, exif.Has(0x927c))
+	}
+}
+
+func TestRationalNormalization(t *testing.T) {
+	tiff := buildTIFF(nil, []testEntry{
+		rationalEntry(0x829d, exifFormatFloat, 0, 0),
+		rationalEntry(0x9204, exifFormatSRational, 1, -3),
+		rationalEntry(0x9201, exifFormatSRational, -3, -10),
+	}, nil)
+
+	exif, err := ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	// FNumber 0/0 has no numeric value.
+	assert.True(t, exif.Has(0x829d))
+	_, ok := exif.Tags[0x829d].(FloatTag)
+	assert.False(t, ok)
+
+	// ExposureBiasValue 1/-3 carries its sign on the numerator.
+	bias, ok := exif.Tags[0x9204].(FloatTag)
+	assert.True(t, ok)
+	assert.Equal(t, -1, bias.Numerator())
+	assert.Equal(t, 3, bias.Denominator())
+
+	// ShutterSpeedValue -3/-10 is positive.
+	shutter, ok := exif.Tags[0x9201].(FloatTag)
+	assert.True(t, ok)
+	assert.Equal(t, 3, shutter.Numerator())
+	assert.Equal(t, 10, shutter.Denominator())
+	assert.Equal(t, []float64{0.3}, shutter.FloatValues())
+}
+
 func TestWriteAndParse(t *testing.T) {
 	exif := New()
 
@@ -43,10 +691,11 @@ func TestWriteAndParse(t *testing.T) {
 
 	defer file.Close()
 
-	_, err = io.Copy(exif, file)
+	// Hide ReadFrom so that io.Copy goes through Write.
+	_, err = io.Copy(struct{ io.Writer }{exif}, file)
 
-	assert.Error(t, err)
-	assert.Equal(t, ErrFoundExifInData, err)
+	assert.NoError(t, err)
+	assert.True(t, exif.Done())
 
 	err = exif.Parse()
 	assert.NoError(t, err)
@@ -56,23 +705,270 @@ func TestWriteAndParse(t *testing.T) {
 	}
 }
 
+func TestClone(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	clone := exif.Clone()
+	assert.Equal(t, exif.Tags, clone.Tags)
+	assert.Equal(t, len(exif.order), len(clone.order))
+	assert.True(t, clone.exifLoader == nil)
+
+	_, ok := clone.Tags[TagOrientation].(IntegerTag)
+	assert.True(t, ok)
+	_, ok = clone.Tags[TagMake].(StringTag)
+	assert.True(t, ok)
+
+	clone.Tags[TagMake].setTextValue("Other")
+	assert.Equal(t, "FUJIFILM", exif.Make())
+	assert.Equal(t, "Other", clone.Make())
+}
+
+func TestKeepRaw(t *testing.T) {
+	exif := New(WithKeepRaw())
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	thumbnail, ok := exif.Thumbnail()
+	assert.True(t, ok)
+	assert.Equal(t, 4354, len(thumbnail))
+	assert.Equal(t, []byte{0xFF, jpegSOI}, thumbnail[:2])
+
+	assert.NoError(t, exif.Close())
+	_, ok = exif.Thumbnail()
+	assert.False(t, ok)
+	assert.NoError(t, exif.Close())
+
+	// Parsed tags outlive Close.
+	assert.Equal(t, "FUJIFILM", exif.Make())
+
+	exif = New()
+	err = exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	_, ok = exif.Thumbnail()
+	assert.False(t, ok)
+}
+
+func TestWriteTo(t *testing.T) {
+	exif := New(WithKeepRaw())
+	defer exif.Close()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	n, err := exif.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+
+	written, err := ReadBytes(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, "FUJIFILM", written.Make())
+	assert.Equal(t, exif.Model(), written.Model())
+	assert.Equal(t, exif.Tags[TagDateTimeOriginal].TextValue(), written.Tags[TagDateTimeOriginal].TextValue())
+
+	// The written data round-trips through a JPEG APP1 segment as well.
+	written, err = ReadBytes(buildJPEG(t, buf.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, "FUJIFILM", written.Make())
+
+	_, err = New().WriteTo(&buf)
+	assert.Equal(t, ErrNoExifData, err)
+
+	// Without WithKeepRaw there is nothing to write.
+	exif, err = Read("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	_, err = exif.WriteTo(&buf)
+	assert.Equal(t, ErrNoExifData, err)
+
+	// A clone shares the kept data.
+	exif = New(WithKeepRaw())
+	defer exif.Close()
+	err = exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	clone := exif.Clone()
+	assert.NoError(t, exif.Close())
+	buf.Reset()
+	_, err = clone.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.NoError(t, clone.Close())
+}
+
+func TestTagsEqual(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	clone := exif.Clone()
+
+	for key, tag := range exif.Tags {
+		assert.True(t, TagsEqual(tag, clone.Tags[key]))
+	}
+	assert.True(t, TagsEqual(nil, nil))
+	assert.False(t, TagsEqual(exif.Tags[TagMake], nil))
+	assert.False(t, TagsEqual(exif.Tags[TagMake], exif.Tags[TagModel]))
+
+	// Tags that print the same but hold different values.
+	clone.Tags[TagOrientation].(*integerTag).intValue = 6
+	assert.False(t, TagsEqual(exif.Tags[TagOrientation], clone.Tags[TagOrientation]))
+	clone.Tags[TagFNumber].(*floatTag).components[0].denominator = 20
+	assert.False(t, TagsEqual(exif.Tags[TagFNumber], clone.Tags[TagFNumber]))
+}
+
+func TestValue(t *testing.T) {
+	const tagExifVersion = 0x9000
+
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	exif, err := Read("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, exif.Tags[TagOrientation].Value())
+	assert.Equal(t, 7.0, exif.Tags[TagFNumber].Value())
+	assert.Equal(t, "FUJIFILM", exif.Tags[TagMake].Value())
+	assert.Equal(t, []byte("0210"), exif.Tags[tagExifVersion].Value())
+
+	// The returned bytes are a copy.
+	exif.Tags[tagExifVersion].Value().([]byte)[0] = 'X'
+	assert.Equal(t, []byte("0210"), exif.Tags[tagExifVersion].Value())
+
+	exif, err = Read("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+	latitude, ok := exif.Tags[TagLatitude].Value().([]float64)
+	assert.True(t, ok)
+	assert.Equal(t, 3, len(latitude))
+	assert.Equal(t, 25.0, latitude[0])
+
+	// Signed integers are sign extended, and integer tags with several
+	// components give all of them.
+	const sbyteTag, sshortTag, slongTag, byteTag, shortTag = 0xBEE0, 0xBEE1, 0xBEE2, 0xBEE3, 0xBEE4
+	tiff := buildTIFF([]testEntry{
+		{sbyteTag, exifFormatSByte, 1, []byte{0xFE}},
+		{sshortTag, exifFormatSShort, 2, []byte{0xFF, 0xFD, 0x00, 0x05}},
+		{slongTag, exifFormatSLong, 1, []byte{0xFF, 0xFF, 0xFF, 0xF9}},
+		{byteTag, exifFormatByte, 3, []byte{1, 2, 3}},
+		shortEntry(shortTag, 8, 8, 8),
+	}, nil, nil)
+	exif = New()
+	exif.SetTagNamer(func(ifd, tag int) (string, bool) {
+		return "Private", true
+	})
+	err = exif.ParseBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	assert.Equal(t, -2, exif.Tags[sbyteTag].Value())
+	assert.Equal(t, []int{-3, 5}, exif.Tags[sshortTag].Value())
+	assert.Equal(t, -3, exif.Tags[sshortTag].(IntegerTag).IntValue())
+	assert.Equal(t, -7, exif.Tags[slongTag].Value())
+	assert.Equal(t, []int{1, 2, 3}, exif.Tags[byteTag].Value())
+	assert.Equal(t, []int{8, 8, 8}, exif.Tags[shortTag].(IntegerTag).IntValues())
+	assert.Equal(t, 0, len(exif.UnhandledFormats()))
+}
+
+func TestWriteContract(t *testing.T) {
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	b, err := os.ReadFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	exif := New()
+
+	// The loader wants more bytes: no error.
+	n, err := exif.Write(b[:16])
+	assert.NoError(t, err)
+	assert.Equal(t, 16, n)
+	assert.False(t, exif.Done())
+
+	n, err = exif.Write(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// The loader has the whole EXIF segment: still no error, Done tells.
+	n, err = exif.Write(b[16:])
+	assert.NoError(t, err)
+	assert.Equal(t, len(b)-16, n)
+	assert.True(t, exif.Done())
+
+	// Later bytes are accepted and ignored.
+	n, err = exif.Write(b[:16])
+	assert.NoError(t, err)
+	assert.Equal(t, 16, n)
+
+	// Behind io.MultiWriter, the other writers get the whole stream.
+	var copied bytes.Buffer
+	tee := New()
+	_, err = io.Copy(io.MultiWriter(tee, &copied), bytes.NewReader(b))
+	assert.NoError(t, err)
+	assert.Equal(t, b, copied.Bytes())
+	assert.True(t, tee.Done())
+	assert.NoError(t, tee.Parse())
+
+	err = exif.Parse()
+	assert.NoError(t, err)
+	assert.False(t, exif.Done())
+	assert.Equal(t, "FUJIFILM", exif.Make())
+}
+
+func TestReadFromAndParse(t *testing.T) {
+	exif := New()
+
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	file, err := os.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	info, err := file.Stat()
+	assert.NoError(t, err)
+
+	n, err := io.Copy(exif, file)
+	assert.NoError(t, err)
+	assert.True(t, n > 0 && n < info.Size())
+
+	err = exif.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, "FUJIFILM", exif.Make())
+}
+
+func TestWriteMaxBytes(t *testing.T) {
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	b, err := os.ReadFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	// The EXIF segment of test.jpg is over 5KB long.
+	exif := New(WithMaxBytes(1024))
+	n, err := exif.Write(b)
+	assert.Equal(t, ErrMaxBytesExceeded, err)
+	assert.Equal(t, 1024, n)
+	assert.False(t, exif.Done())
+
+	n, err = exif.Write(b[n:])
+	assert.Equal(t, ErrMaxBytesExceeded, err)
+	assert.Equal(t, 0, n)
+
+	exif = New(WithMaxBytes(64 << 10))
+	_, err = exif.Write(b)
+	assert.NoError(t, err)
+	assert.True(t, exif.Done())
+	assert.NoError(t, exif.Parse())
+}
+
 func TestGetLongitude(t *testing.T) {
 	exif := New()
 	err := exif.Open("_examples/resources/testlocation.jpg")
 	assert.NoError(t, err)
 
-	longitude, ok := exif.Tags["Longitude"]
+	longitude, ok := exif.Tags[TagLongitude].(FloatTag)
 	assert.True(t, ok)
 
-	assert.Equal(t, "131,  0, 55.2063", longitude)
+	assert.Equal(t, "131,  0, 55.2063", longitude.TextValue())
+	assert.Equal(t, 131.0, longitude.FloatValue())
+	assert.InDeltaSlice(t, []float64{131, 0, 55.2063}, longitude.FloatValues(), 1e-9)
 }
 
 func TestGetLatitude(t *testing.T) {
 	exif := New()
 	err := exif.Open("_examples/resources/testlocation.jpg")
 	assert.NoError(t, err)
-	latitude, ok := exif.Tags["Latitude"]
+	latitude, ok := exif.Tags[TagLatitude].(FloatTag)
 	assert.True(t, ok)
 
-	assert.Equal(t, "25, 21, 32.6101", latitude)
+	assert.Equal(t, "25, 21, 32.6101", latitude.TextValue())
+	assert.Equal(t, 25.0, latitude.FloatValue())
+	assert.InDeltaSlice(t, []float64{25, 21, 32.6101}, latitude.FloatValues(), 1e-9)
 }
@@ -0,0 +1,37 @@
+package exif
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func FuzzParse(f *testing.F) {
+	for _, file := range []string{"_examples/resources/test.jpg", "_examples/resources/testlocation.jpg"} {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	tiff := buildTIFF([]testEntry{asciiEntry(TagMake, "Maker")}, []testEntry{shortEntry(TagISOSpeedRatings, 100)}, nil)
+	f.Add(tiff)
+	f.Add(buildPNG(tiff))
+	f.Add(buildWebP(tiff))
+	f.Add(buildHEIC(tiff, false))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		exif, err := ReadBytes(b)
+		if err == nil {
+			for _, tag := range exif.Tags {
+				tag.TextValue()
+			}
+			exif.RawEntries()
+		}
+
+		// Parse also releases the loader when ReadFrom fails.
+		streamed := New()
+		streamed.ReadFrom(bytes.NewReader(b))
+		streamed.Parse()
+	})
+}
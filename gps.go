@@ -0,0 +1,191 @@
+package exif
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+const gpsDateStampLayout = "2006:01:02"
+
+// GPSInfo is the position recorded in the GPS IFD.
+type GPSInfo struct {
+	// Latitude in decimal degrees, negative south of the equator.
+	Latitude float64
+	// Longitude in decimal degrees, negative west of Greenwich.
+	Longitude float64
+	// Altitude in meters, negative below sea level. It is only set if
+	// HasAltitude is true.
+	Altitude    float64
+	HasAltitude bool
+	// LatitudeRefInferred and LongitudeRefInferred report that the
+	// GPSLatitudeRef or GPSLongitudeRef tag was missing or unreadable, and
+	// that north or east was assumed.
+	LatitudeRefInferred  bool
+	LongitudeRefInferred bool
+}
+
+// GPS returns the position of the GPS IFD. It needs the GPSLatitude and
+// GPSLongitude tags; their reference tags default to north and east, which is
+// flagged on the returned GPSInfo.
+func (d *Data) GPS() (GPSInfo, bool) {
+	tags := d.ifdTags[IFDGPS]
+	var info GPSInfo
+
+	latitude, ok := gpsDegrees(tags[TagLatitude])
+	if !ok {
+		return GPSInfo{}, false
+	}
+	longitude, ok := gpsDegrees(tags[TagLongitude])
+	if !ok {
+		return GPSInfo{}, false
+	}
+
+	info.Latitude, info.LatitudeRefInferred = applyGPSRef(latitude, tags[TagLatitudeRef], "N", "S")
+	info.Longitude, info.LongitudeRefInferred = applyGPSRef(longitude, tags[TagLongitudeRef], "E", "W")
+
+	if altitude, ok := tags[TagAltitude].(FloatTag); ok {
+		info.Altitude = altitude.FloatValue()
+		info.HasAltitude = true
+		if ref, ok := tags[TagAltitudeRef].(IntegerTag); ok && ref.IntValue() == 1 {
+			info.Altitude = -info.Altitude
+		}
+	}
+
+	return info, true
+}
+
+// gpsSpeedUnits names the values of the GPSSpeedRef tag.
+var gpsSpeedUnits = map[string]string{
+	"K": "km/h",
+	"M": "mph",
+	"N": "knots",
+}
+
+// GPSSpeed returns the speed of the GPS receiver along with its unit, one of
+// "km/h", "mph" or "knots". GPSSpeedRef defaults to km/h when it is missing.
+func (d *Data) GPSSpeed() (float64, string, bool) {
+	tags := d.ifdTags[IFDGPS]
+	speed, ok := tags[TagGPSSpeed].(FloatTag)
+	if !ok {
+		return 0, "", false
+	}
+	ref := "K"
+	if tag, ok := tags[TagGPSSpeedRef]; ok {
+		ref = strings.ToUpper(strings.TrimSpace(tag.TextValue()))
+	}
+	unit, ok := gpsSpeedUnits[ref]
+	if !ok {
+		return 0, "", false
+	}
+	return speed.FloatValue(), unit, true
+}
+
+// gpsNorths names the values of the GPSImgDirectionRef and GPSTrackRef tags.
+var gpsNorths = map[string]string{
+	"T": "true",
+	"M": "magnetic",
+}
+
+// GPSImageDirection returns the direction the camera was pointing at, in
+// degrees from 0 to 359.99, along with the north it is relative to, "true" or
+// "magnetic" as given by GPSImgDirectionRef. The north is empty when
+// GPSImgDirectionRef is missing.
+func (d *Data) GPSImageDirection() (float64, string, bool) {
+	return d.gpsDirection(TagGPSImgDirection, TagGPSImgDirectionRef)
+}
+
+// GPSTrack returns the direction the GPS receiver was moving in, in degrees
+// from 0 to 359.99, along with the north it is relative to, "true" or
+// "magnetic" as given by GPSTrackRef. The north is empty when GPSTrackRef is
+// missing.
+func (d *Data) GPSTrack() (float64, string, bool) {
+	return d.gpsDirection(TagGPSTrack, TagGPSTrackRef)
+}
+
+func (d *Data) gpsDirection(tag, refTag int) (float64, string, bool) {
+	tags := d.ifdTags[IFDGPS]
+	direction, ok := tags[tag].(FloatTag)
+	if !ok || direction.FloatValue() < 0 || direction.FloatValue() >= 360 {
+		return 0, "", false
+	}
+	var north string
+	if ref, ok := tags[refTag]; ok {
+		north, ok = gpsNorths[strings.ToUpper(strings.TrimSpace(ref.TextValue()))]
+		if !ok {
+			return 0, "", false
+		}
+	}
+	return direction.FloatValue(), north, true
+}
+
+// gpsDegrees converts the degrees, minutes and seconds of a GPSLatitude or
+// GPSLongitude tag to decimal degrees. Minutes and seconds may be left out.
+func gpsDegrees(tag Tag) (float64, bool) {
+	floatTag, ok := tag.(FloatTag)
+	if !ok {
+		return 0, false
+	}
+	values := floatTag.FloatValues()
+	if len(values) == 0 || len(values) > 3 {
+		return 0, false
+	}
+
+	var degrees float64
+	unit := 1.0
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+			return 0, false
+		}
+		degrees += v / unit
+		unit *= 60
+	}
+	return degrees, true
+}
+
+// applyGPSRef negates degrees when ref holds the negative reference, and
+// reports whether ref was missing or held neither reference.
+func applyGPSRef(degrees float64, ref Tag, positive, negative string) (float64, bool) {
+	if ref == nil {
+		return degrees, true
+	}
+	switch strings.ToUpper(strings.TrimSpace(ref.TextValue())) {
+	case positive:
+		return degrees, false
+	case negative:
+		return -degrees, false
+	}
+	return degrees, true
+}
+
+// GPSDateTime returns the UTC time of the GPS fix, combining the GPSDateStamp
+// and GPSTimeStamp tags. The second component of GPSTimeStamp may carry a
+// fractional part.
+func (d *Data) GPSDateTime() (time.Time, bool) {
+	tags := d.ifdTags[IFDGPS]
+	dateTag, ok := tags[TagGPSDateStamp]
+	if !ok {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation(gpsDateStampLayout, dateTag.TextValue(), time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	timeTag, ok := tags[TagGPSTimeStamp].(FloatTag)
+	if !ok {
+		return time.Time{}, false
+	}
+	hms := timeTag.FloatValues()
+	if len(hms) != 3 {
+		return time.Time{}, false
+	}
+	for _, v := range hms {
+		if math.IsNaN(v) || math.IsInf(v, 0) || v < 0 {
+			return time.Time{}, false
+		}
+	}
+
+	seconds := hms[0]*3600 + hms[1]*60 + hms[2]
+	return date.Add(time.Duration(math.Round(seconds * float64(time.Second)))), true
+}
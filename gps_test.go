@@ -0,0 +1,123 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestGPSDateTime(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+
+	dateTime, ok := exif.GPSDateTime()
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2014, 4, 27, 8, 44, 31, 0, time.UTC), dateTime)
+
+	// Only the tags of the GPS IFD are read, not those of Tags.
+	delete(exif.ifdTags[IFDGPS], TagGPSDateStamp)
+	_, ok = exif.GPSDateTime()
+	assert.False(t, ok)
+}
+
+func TestGPSDateTimeMissing(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	_, ok := exif.GPSDateTime()
+	assert.False(t, ok)
+}
+
+func TestGPS(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+
+	info, ok := exif.GPS()
+	assert.True(t, ok)
+	assert.InDelta(t, -(25 + 21.0/60 + 32.6101/3600), info.Latitude, 1e-9)
+	assert.InDelta(t, 131+55.2063/3600, info.Longitude, 1e-9)
+	assert.True(t, info.HasAltitude)
+	assert.InDelta(t, 492.0, info.Altitude, 1e-9)
+	assert.False(t, info.LatitudeRefInferred)
+	assert.False(t, info.LongitudeRefInferred)
+
+	exif = New()
+	err = exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	_, ok = exif.GPS()
+	assert.False(t, ok)
+}
+
+func TestGPSInferredRef(t *testing.T) {
+	tiff := buildTIFF(nil, nil, []testEntry{
+		asciiEntry(TagLongitudeRef, "W"),
+		rationalEntry(TagLatitude, exifFormatFloat, 40, 1, 26, 1, 46, 1),
+		rationalEntry(TagLongitude, exifFormatFloat, 79, 1, 58, 1, 56, 1),
+		{TagAltitudeRef, exifFormatByte, 1, []byte{1}},
+		rationalEntry(TagAltitude, exifFormatFloat, 28, 1),
+	})
+	exif, err := ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	info, ok := exif.GPS()
+	assert.True(t, ok)
+	assert.InDelta(t, 40.446, info.Latitude, 1e-3)
+	assert.True(t, info.LatitudeRefInferred)
+
+	// The components of the tag are kept as they are, only GPS converts them.
+	assert.Equal(t, 40.0, exif.Tags[TagLatitude].(FloatTag).FloatValue())
+	assert.InDelta(t, -79.982, info.Longitude, 1e-3)
+	assert.False(t, info.LongitudeRefInferred)
+	assert.Equal(t, -28.0, info.Altitude)
+}
+
+func TestGPSSpeedAndDirection(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+
+	direction, north, ok := exif.GPSImageDirection()
+	assert.True(t, ok)
+	assert.Equal(t, 303.0, direction)
+	assert.Equal(t, "magnetic", north)
+
+	_, _, ok = exif.GPSSpeed()
+	assert.False(t, ok)
+
+	tiff := buildTIFF(nil, nil, []testEntry{
+		asciiEntry(TagGPSSpeedRef, "N"),
+		rationalEntry(TagGPSSpeed, exifFormatFloat, 125, 10),
+		asciiEntry(TagGPSTrackRef, "T"),
+		rationalEntry(TagGPSTrack, exifFormatFloat, 9050, 100),
+	})
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	speed, unit, ok := exif.GPSSpeed()
+	assert.True(t, ok)
+	assert.Equal(t, 12.5, speed)
+	assert.Equal(t, "knots", unit)
+
+	track, north, ok := exif.GPSTrack()
+	assert.True(t, ok)
+	assert.Equal(t, 90.5, track)
+	assert.Equal(t, "true", north)
+
+	_, _, ok = exif.GPSImageDirection()
+	assert.False(t, ok)
+
+	tiff = buildTIFF(nil, nil, []testEntry{
+		rationalEntry(TagGPSImgDirection, exifFormatFloat, 45, 1),
+	})
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	// Without its reference the north is unknown.
+	direction, north, ok = exif.GPSImageDirection()
+	assert.True(t, ok)
+	assert.Equal(t, 45.0, direction)
+	assert.Equal(t, "", north)
+}
@@ -0,0 +1,325 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// heifBrands holds the ftyp major brands of HEIF images, HEIC included.
+var heifBrands = map[string]bool{
+	"heic": true,
+	"heix": true,
+	"heim": true,
+	"heis": true,
+	"hevc": true,
+	"hevx": true,
+	"mif1": true,
+	"msf1": true,
+}
+
+// isoBox is a box of an ISO base media file.
+type isoBox struct {
+	boxType string
+	payload []byte
+}
+
+// isoReader reads big endian fields from the payload of a box, remembering
+// whether it ran out of data.
+type isoReader struct {
+	b   []byte
+	bad bool
+}
+
+// uint reads an n bytes wide unsigned integer, n being at most 8.
+func (r *isoReader) uint(n int) uint64 {
+	if r.bad || n > len(r.b) {
+		r.bad = true
+		return 0
+	}
+	var v uint64
+	for _, c := range r.b[:n] {
+		v = v<<8 | uint64(c)
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+// skipString skips a null terminated string.
+func (r *isoReader) skipString() {
+	i := bytes.IndexByte(r.b, 0)
+	if r.bad || i < 0 {
+		r.bad = true
+		return
+	}
+	r.b = r.b[i+1:]
+}
+
+// isHEIF reports whether b starts with the ftyp box of a HEIF image.
+func isHEIF(b []byte) bool {
+	return len(b) >= 12 && string(b[4:8]) == "ftyp" && heifBrands[string(b[8:12])]
+}
+
+// isoBoxes splits b into the boxes it holds.
+func isoBoxes(b []byte) ([]isoBox, bool) {
+	var boxes []isoBox
+	for len(b) > 0 {
+		r := isoReader{b: b}
+		size := r.uint(4)
+		r.uint(4)
+		if r.bad {
+			return nil, false
+		}
+		boxType := string(b[4:8])
+		header := uint64(8)
+		switch size {
+		case 0:
+			// The box extends to the end of the file.
+			size = uint64(len(b))
+		case 1:
+			size = r.uint(8)
+			header = 16
+		}
+		if r.bad || size < header || size > uint64(len(b)) {
+			return nil, false
+		}
+		boxes = append(boxes, isoBox{boxType, b[header:size]})
+		b = b[size:]
+	}
+	return boxes, true
+}
+
+// readBox returns the payload of the first top-level box of the given type in
+// a file, reading only the headers of the boxes before it.
+func readBox(src *source, boxType string) ([]byte, bool) {
+	offset := uint64(0)
+	for offset < uint64(src.size) {
+		header, ok := src.read(offset, 8)
+		if !ok {
+			return nil, false
+		}
+		size := uint64(binary.BigEndian.Uint32(header))
+		headerSize := uint64(8)
+		switch size {
+		case 0:
+			// The box extends to the end of the file.
+			size = uint64(src.size) - offset
+		case 1:
+			large, ok := src.read(offset+8, 8)
+			if !ok {
+				return nil, false
+			}
+			size = binary.BigEndian.Uint64(large)
+			headerSize = 16
+		}
+		if size < headerSize || size > uint64(src.size)-offset {
+			return nil, false
+		}
+		if string(header[4:8]) == boxType {
+			return src.read(offset+headerSize, size-headerSize)
+		}
+		offset += size
+	}
+	return nil, false
+}
+
+// findBox returns the payload of the first box of the given type.
+func findBox(boxes []isoBox, boxType string) ([]byte, bool) {
+	for _, box := range boxes {
+		if box.boxType == boxType {
+			return box.payload, true
+		}
+	}
+	return nil, false
+}
+
+// heifExifItem returns the ID of the Exif item listed in an iinf box.
+func heifExifItem(iinf []byte) (uint64, bool) {
+	r := isoReader{b: iinf}
+	version := r.uint(1)
+	r.uint(3)
+	if version == 0 {
+		r.uint(2)
+	} else {
+		r.uint(4)
+	}
+	if r.bad {
+		return 0, false
+	}
+
+	entries, ok := isoBoxes(r.b)
+	if !ok {
+		return 0, false
+	}
+	for _, entry := range entries {
+		if entry.boxType != "infe" {
+			continue
+		}
+		e := isoReader{b: entry.payload}
+		version := e.uint(1)
+		e.uint(3)
+		if version < 2 {
+			// Older item entries have no item type.
+			continue
+		}
+		var id uint64
+		if version == 2 {
+			id = e.uint(2)
+		} else {
+			id = e.uint(4)
+		}
+		e.uint(2)
+		itemType := e.uint(4)
+		if !e.bad && itemType == uint64(binary.BigEndian.Uint32([]byte("Exif"))) {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// heifItemData returns the data of an item, gathering the extents listed in
+// the iloc box from the file or from the idat box. An extent of the file
+// without a length, which runs to the end of the file, is cut to limit bytes;
+// with a limit of 0 it is not read at all.
+func heifItemData(file *source, iloc, idat []byte, item, limit uint64) ([]byte, bool) {
+	r := isoReader{b: iloc}
+	version := r.uint(1)
+	r.uint(3)
+	sizes := r.uint(1)
+	offsetSize, lengthSize := int(sizes>>4), int(sizes&0x0f)
+	sizes = r.uint(1)
+	baseOffsetSize, indexSize := int(sizes>>4), 0
+	if version == 1 || version == 2 {
+		indexSize = int(sizes & 0x0f)
+	}
+	idSize := 2
+	if version == 2 {
+		idSize = 4
+	}
+	count := r.uint(idSize)
+
+	for i := uint64(0); i < count && !r.bad; i++ {
+		id := r.uint(idSize)
+		var method uint64
+		if version == 1 || version == 2 {
+			method = r.uint(2) & 0x0f
+		}
+		r.uint(2)
+		base := r.uint(baseOffsetSize)
+		extents := r.uint(2)
+
+		var data []byte
+		for j := uint64(0); j < extents && !r.bad; j++ {
+			r.uint(indexSize)
+			offset := base + r.uint(offsetSize)
+			length := r.uint(lengthSize)
+			if id != item {
+				continue
+			}
+			src := file
+			if method == 1 {
+				src = bytesSource(idat)
+			} else if method != 0 {
+				return nil, false
+			}
+			if offset > uint64(src.size) {
+				return nil, false
+			}
+			if length == 0 {
+				// The extent extends to the end of the source.
+				length = uint64(src.size) - offset
+				if method == 0 {
+					if limit == 0 {
+						return nil, false
+					}
+					if length > limit {
+						length = limit
+					}
+				}
+			}
+			extent, ok := src.read(offset, length)
+			if !ok {
+				return nil, false
+			}
+			data = append(data, extent...)
+		}
+		if id == item && !r.bad {
+			return data, true
+		}
+	}
+	return nil, false
+}
+
+// heifMeta returns the boxes held by the meta box of a HEIF image.
+func heifMeta(src *source) ([]isoBox, bool) {
+	ftyp, _ := src.read(0, 12)
+	if !isHEIF(ftyp) {
+		return nil, false
+	}
+	meta, ok := readBox(src, "meta")
+	if !ok || len(meta) < 4 {
+		return nil, false
+	}
+	// The meta box is a full box, the version and flags come first.
+	return isoBoxes(meta[4:])
+}
+
+// heifHasExif reports whether a HEIF image lists an Exif item, without
+// reading the item.
+func heifHasExif(src *source) bool {
+	children, ok := heifMeta(src)
+	if !ok {
+		return false
+	}
+	iinf, ok := findBox(children, "iinf")
+	if !ok {
+		return false
+	}
+	_, ok = heifExifItem(iinf)
+	return ok
+}
+
+// heifExif returns the TIFF structure held by the Exif item of a HEIF image.
+// limit bounds an extent of the item that runs to the end of the file, see
+// heifItemData.
+func heifExif(src *source, limit uint64) ([]byte, bool) {
+	children, ok := heifMeta(src)
+	if !ok {
+		return nil, false
+	}
+
+	iinf, ok := findBox(children, "iinf")
+	if !ok {
+		return nil, false
+	}
+	item, ok := heifExifItem(iinf)
+	if !ok {
+		return nil, false
+	}
+	iloc, ok := findBox(children, "iloc")
+	if !ok {
+		return nil, false
+	}
+	idat, _ := findBox(children, "idat")
+	data, ok := heifItemData(src, iloc, idat, item, limit)
+	if !ok {
+		return nil, false
+	}
+
+	// The item starts with the offset of the TIFF header that follows it.
+	r := isoReader{b: data}
+	offset := r.uint(4)
+	if r.bad || offset > uint64(len(r.b)) {
+		return nil, false
+	}
+	return r.b[offset:], true
+}
+
+// parseHEIF loads the EXIF data of a HEIF image, stored in its Exif item.
+func (d *Data) parseHEIF(src *source) error {
+	d.startParse()
+	payload, ok := heifExif(src, uint64(d.maxBytes))
+	if !ok {
+		return ErrNoExifData
+	}
+	return d.parseTIFF(exifSource(payload))
+}
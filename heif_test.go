@@ -0,0 +1,87 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func isoBoxBytes(boxType string, payload ...[]byte) []byte {
+	b := make([]byte, 8)
+	copy(b[4:], boxType)
+	for _, p := range payload {
+		b = append(b, p...)
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+// buildHEIC builds a HEIC container holding tiff in an Exif item, stored in
+// the mdat box or, if inIdat is set, in the idat box of the meta box. The
+// image itself is left out.
+func buildHEIC(tiff []byte, inIdat bool) []byte {
+	ftyp := isoBoxBytes("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	if tiff == nil {
+		return append(ftyp, isoBoxBytes("meta", []byte{0, 0, 0, 0})...)
+	}
+
+	item := append([]byte{0, 0, 0, 6}, exifHeader...)
+	item = append(item, tiff...)
+
+	infe := isoBoxBytes("infe", []byte{2, 0, 0, 0, 0, 1, 0, 0}, []byte("Exif\x00"))
+	iinf := isoBoxBytes("iinf", []byte{0, 0, 0, 0, 0, 1}, infe)
+
+	// Version 1 carries the construction method, the offset of the single
+	// extent is patched in once the layout is known.
+	iloc := []byte{1, 0, 0, 0, 0x44, 0x00, 0, 1, 0, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(iloc[20:], uint32(len(item)))
+
+	var meta, mdat []byte
+	if inIdat {
+		iloc[11] = 1
+		meta = isoBoxBytes("meta", []byte{0, 0, 0, 0}, iinf, isoBoxBytes("iloc", iloc), isoBoxBytes("idat", item))
+	} else {
+		meta = isoBoxBytes("meta", []byte{0, 0, 0, 0}, iinf, isoBoxBytes("iloc", iloc))
+		offset := len(ftyp) + len(meta) + 8
+		binary.BigEndian.PutUint32(iloc[16:], uint32(offset))
+		meta = isoBoxBytes("meta", []byte{0, 0, 0, 0}, iinf, isoBoxBytes("iloc", iloc))
+		mdat = isoBoxBytes("mdat", item)
+	}
+
+	b := append(ftyp, meta...)
+	return append(b, mdat...)
+}
+
+func TestReadHEICWithoutExif(t *testing.T) {
+	_, err := ReadBytes(buildHEIC(nil, false))
+	assert.Equal(t, ErrNoExifData, err)
+}
+
+func TestReadHEICExtentToEnd(t *testing.T) {
+	tiff := buildTIFF([]testEntry{asciiEntry(TagModel, "Phone")}, nil, nil)
+	toEnd := func(inIdat bool) []byte {
+		b := buildHEIC(tiff, inIdat)
+		iloc := bytes.Index(b, []byte("iloc")) + 4
+		binary.BigEndian.PutUint32(b[iloc+20:], 0)
+		return b
+	}
+
+	// The extent of an item in the idat box ends with the box.
+	exif, err := ReadBytes(toEnd(true))
+	assert.NoError(t, err)
+	assert.Equal(t, "Phone", exif.Model())
+
+	// An extent running to the end of the file is only read with a limit,
+	// and no further than it.
+	_, err = ReadBytes(toEnd(false))
+	assert.Equal(t, ErrNoExifData, err)
+
+	exif = New(WithMaxBytes(1 << 16))
+	err = exif.ParseBytes(toEnd(false))
+	assert.NoError(t, err)
+	assert.Equal(t, "Phone", exif.Model())
+
+	err = New(WithMaxBytes(16)).ParseBytes(toEnd(false))
+	assert.Error(t, err)
+}
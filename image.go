@@ -0,0 +1,129 @@
+package exif
+
+// resolutionUnits names the values of the ResolutionUnit tag.
+var resolutionUnits = map[int]string{
+	1: "none",
+	2: "inches",
+	3: "centimeters",
+}
+
+// compressions names the values of the Compression tag.
+var compressions = map[int]string{
+	1:     "Uncompressed",
+	2:     "CCITT 1D",
+	3:     "T4/Group 3 Fax",
+	4:     "T6/Group 4 Fax",
+	5:     "LZW",
+	6:     "JPEG (old-style)",
+	7:     "JPEG",
+	8:     "Adobe Deflate",
+	32773: "PackBits",
+	32946: "Deflate",
+	34892: "Lossy JPEG",
+}
+
+// photometricInterpretations names the values of the PhotometricInterpretation
+// tag.
+var photometricInterpretations = map[int]string{
+	0:     "WhiteIsZero",
+	1:     "BlackIsZero",
+	2:     "RGB",
+	3:     "RGB Palette",
+	4:     "Transparency Mask",
+	5:     "CMYK",
+	6:     "YCbCr",
+	8:     "CIELab",
+	9:     "ICCLab",
+	10:    "ITULab",
+	32803: "Color Filter Array",
+	34892: "Linear Raw",
+}
+
+// planarConfigurations names the values of the PlanarConfiguration tag.
+var planarConfigurations = map[int]string{
+	1: "Chunky",
+	2: "Planar",
+}
+
+// Resolution returns the number of pixels per unit in the width and height
+// directions of the main image, along with the name of the unit: "inches",
+// "centimeters", or "none" when the image has no absolute unit. ResolutionUnit
+// defaults to inches when it is not present.
+func (d *Data) Resolution() (x, y float64, unit string, ok bool) {
+	tags := d.ifdTags[IFD0]
+	xTag, ok := tags[TagXResolution].(FloatTag)
+	if !ok {
+		return 0, 0, "", false
+	}
+	yTag, ok := tags[TagYResolution].(FloatTag)
+	if !ok {
+		return 0, 0, "", false
+	}
+
+	unitValue := 2
+	if unitTag, present := tags[TagResolutionUnit]; present {
+		intTag, isInt := unitTag.(IntegerTag)
+		if !isInt {
+			return 0, 0, "", false
+		}
+		unitValue = intTag.IntValue()
+	}
+	unit, ok = resolutionUnits[unitValue]
+	if !ok {
+		return 0, 0, "", false
+	}
+	return xTag.FloatValue(), yTag.FloatValue(), unit, true
+}
+
+// DisplayDimensions returns the width and height of the image as displayed,
+// read from PixelXDimension and PixelYDimension and swapped when the
+// Orientation of the image turns it by 90 or 270 degrees.
+func (d *Data) DisplayDimensions() (w, h int, ok bool) {
+	x, ok := d.Tags[TagPixelXDimension].(IntegerTag)
+	if !ok {
+		return 0, 0, false
+	}
+	y, ok := d.Tags[TagPixelYDimension].(IntegerTag)
+	if !ok {
+		return 0, 0, false
+	}
+
+	w, h = x.IntValue(), y.IntValue()
+	if orientation, ok := d.ifdTags[IFD0][TagOrientation].(IntegerTag); ok {
+		switch orientation.IntValue() {
+		case OrientationLeftTop, OrientationRightTop, OrientationRightBottom, OrientationLeftBottom:
+			w, h = h, w
+		}
+	}
+	return w, h, true
+}
+
+// Compression returns the compression scheme of the main image, like
+// "Uncompressed", "LZW" or "JPEG". The compression of the thumbnail is found
+// in the tags of IFD1.
+func (d *Data) Compression() (string, bool) {
+	return d.ifdEnumValue(IFD0, TagCompression, compressions)
+}
+
+// PhotometricInterpretation returns the color space of the pixel data of the
+// main image, like "RGB", "YCbCr" or "Color Filter Array".
+func (d *Data) PhotometricInterpretation() (string, bool) {
+	return d.ifdEnumValue(IFD0, TagPhotometricInterpretation, photometricInterpretations)
+}
+
+// SamplesPerPixel returns the number of components per pixel of the main
+// image, such as 3 for RGB.
+func (d *Data) SamplesPerPixel() (int, bool) {
+	samples, ok := d.ifdTags[IFD0][TagSamplesPerPixel].(IntegerTag)
+	if !ok {
+		return 0, false
+	}
+	return samples.IntValue(), true
+}
+
+// PlanarConfiguration returns "Chunky" when the components of each pixel are
+// stored together, or "Planar" when each component is stored in its own
+// plane.
+func (d *Data) PlanarConfiguration() (string, bool) {
+	return d.ifdEnumValue(IFD0, TagPlanarConfiguration, planarConfigurations)
+}
@@ -0,0 +1,107 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestResolution(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	x, y, unit, ok := exif.Resolution()
+	assert.True(t, ok)
+	assert.Equal(t, 72.0, x)
+	assert.Equal(t, 72.0, y)
+	assert.Equal(t, "inches", unit)
+
+	tiff := buildTIFF([]testEntry{
+		rationalEntry(TagXResolution, exifFormatFloat, 1181, 10),
+		rationalEntry(TagYResolution, exifFormatFloat, 1181, 10),
+		shortEntry(TagResolutionUnit, 3),
+	}, nil, nil)
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	x, y, unit, ok = exif.Resolution()
+	assert.True(t, ok)
+	assert.Equal(t, 118.1, x)
+	assert.Equal(t, 118.1, y)
+	assert.Equal(t, "centimeters", unit)
+
+	tiff = buildTIFF([]testEntry{
+		rationalEntry(TagXResolution, exifFormatFloat, 300, 1),
+		rationalEntry(TagYResolution, exifFormatFloat, 300, 1),
+		shortEntry(TagResolutionUnit, 9),
+	}, nil, nil)
+	exif, err = ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	_, _, _, ok = exif.Resolution()
+	assert.False(t, ok)
+}
+
+func TestDisplayDimensions(t *testing.T) {
+	exif := New()
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	w, h, ok := exif.DisplayDimensions()
+	assert.True(t, ok)
+	assert.Equal(t, 640, w)
+	assert.Equal(t, 480, h)
+
+	for orientation := OrientationTopLeft; orientation <= OrientationLeftBottom; orientation++ {
+		tiff := buildTIFF(
+			[]testEntry{shortEntry(TagOrientation, orientation)},
+			[]testEntry{longEntry(TagPixelXDimension, 4000), longEntry(TagPixelYDimension, 3000)},
+			nil,
+		)
+		exif, err := ReadBytes(buildJPEG(t, tiff))
+		assert.NoError(t, err)
+
+		w, h, ok := exif.DisplayDimensions()
+		assert.True(t, ok)
+		if orientation >= OrientationLeftTop {
+			assert.Equal(t, []int{3000, 4000}, []int{w, h})
+		} else {
+			assert.Equal(t, []int{4000, 3000}, []int{w, h})
+		}
+	}
+}
+
+func TestPixelLayout(t *testing.T) {
+	tiff := buildTIFF([]testEntry{
+		shortEntry(TagCompression, 5),
+		shortEntry(TagPhotometricInterpretation, 2),
+		shortEntry(TagSamplesPerPixel, 3),
+		shortEntry(TagPlanarConfiguration, 1),
+	}, nil, nil)
+	exif, err := ReadBytes(tiff)
+	assert.NoError(t, err)
+
+	compression, ok := exif.Compression()
+	assert.True(t, ok)
+	assert.Equal(t, "LZW", compression)
+
+	photometric, ok := exif.PhotometricInterpretation()
+	assert.True(t, ok)
+	assert.Equal(t, "RGB", photometric)
+
+	samples, ok := exif.SamplesPerPixel()
+	assert.True(t, ok)
+	assert.Equal(t, 3, samples)
+
+	planar, ok := exif.PlanarConfiguration()
+	assert.True(t, ok)
+	assert.Equal(t, "Chunky", planar)
+
+	// The JPEG thumbnail's compression is not the main image's.
+	exif, err = Read("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	_, ok = exif.Compression()
+	assert.False(t, ok)
+	_, ok = exif.SamplesPerPixel()
+	assert.False(t, ok)
+}
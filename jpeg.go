@@ -0,0 +1,296 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	jpegMarkerPrefix = 0xFF
+	jpegSOI          = 0xD8
+	jpegEOI          = 0xD9
+	jpegSOS          = 0xDA
+	jpegRST0         = 0xD0
+	jpegRST7         = 0xD7
+	jpegTEM          = 0x01
+	jpegAPP0         = 0xE0
+	jpegAPP1         = 0xE1
+)
+
+var (
+	exifHeader = []byte("Exif\x00\x00")
+	jfxxHeader = []byte("JFXX\x00")
+	xmpHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// jpegSegment is a marker segment in the header of a JPEG stream.
+type jpegSegment struct {
+	marker  byte
+	start   int
+	end     int
+	payload []byte
+}
+
+func (s jpegSegment) isExif() bool {
+	return s.marker == jpegAPP1 && bytes.HasPrefix(s.payload, exifHeader)
+}
+
+func (s jpegSegment) isJFXX() bool {
+	return s.marker == jpegAPP0 && bytes.HasPrefix(s.payload, jfxxHeader)
+}
+
+// jpegSegments splits the header of a JPEG stream into marker segments. It
+// stops at the start of scan (or end of image) marker and returns its offset;
+// everything from there on is entropy-coded data that is never inspected.
+func jpegSegments(b []byte) ([]jpegSegment, int, error) {
+	if len(b) < 2 || b[0] != jpegMarkerPrefix || b[1] != jpegSOI {
+		return nil, 0, ErrNotJPEG
+	}
+
+	var segments []jpegSegment
+	i := 2
+	for {
+		if i+1 >= len(b) || b[i] != jpegMarkerPrefix {
+			return nil, 0, ErrMalformedJPEG
+		}
+		marker := b[i+1]
+		switch {
+		case marker == jpegMarkerPrefix:
+			// Fill byte.
+			i++
+			continue
+		case marker == jpegSOS || marker == jpegEOI:
+			return segments, i, nil
+		case marker == jpegTEM || (marker >= jpegRST0 && marker <= jpegRST7):
+			// Standalone markers carry no length.
+			segments = append(segments, jpegSegment{marker: marker, start: i, end: i + 2})
+			i += 2
+			continue
+		}
+
+		if i+4 > len(b) {
+			return nil, 0, ErrMalformedJPEG
+		}
+		length := int(binary.BigEndian.Uint16(b[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(b) {
+			return nil, 0, ErrMalformedJPEG
+		}
+		segments = append(segments, jpegSegment{marker: marker, start: i, end: end, payload: b[i+4 : end]})
+		i = end
+	}
+}
+
+// jpegXMP looks for the XMP packet in the header of a JPEG stream. Unlike
+// jpegSegments it tolerates a stream that ends early: more reports whether
+// the packet may still follow in bytes not seen yet.
+func jpegXMP(b []byte) (xmp []byte, more bool) {
+	xmp, _, more = jpegXMPFrom(b, 0)
+	return xmp, more
+}
+
+// jpegXMPFrom goes on with the search of jpegXMP at offset i of b, either 0 or
+// the start of a segment. When more bytes are needed, next is the offset to
+// resume from once they are appended to b, so that complete segments are not
+// scanned again.
+func jpegXMPFrom(b []byte, i int) (xmp []byte, next int, more bool) {
+	if i == 0 {
+		if len(b) < 2 {
+			return nil, 0, len(b) == 0 || b[0] == jpegMarkerPrefix
+		}
+		if b[0] != jpegMarkerPrefix || b[1] != jpegSOI {
+			return nil, 0, false
+		}
+		i = 2
+	}
+
+	for {
+		if i+1 >= len(b) {
+			return nil, i, true
+		}
+		if b[i] != jpegMarkerPrefix {
+			return nil, i, false
+		}
+		marker := b[i+1]
+		switch {
+		case marker == jpegMarkerPrefix:
+			i++
+			continue
+		case marker == jpegSOS || marker == jpegEOI:
+			return nil, i, false
+		case marker == jpegTEM || (marker >= jpegRST0 && marker <= jpegRST7):
+			i += 2
+			continue
+		}
+
+		if i+4 > len(b) {
+			return nil, i, true
+		}
+		length := int(binary.BigEndian.Uint16(b[i+2:]))
+		if length < 2 {
+			return nil, i, false
+		}
+		end := i + 2 + length
+		if end > len(b) {
+			return nil, i, true
+		}
+		payload := b[i+4 : end]
+		if marker == jpegAPP1 && bytes.HasPrefix(payload, xmpHeader) {
+			return payload[len(xmpHeader):], i, false
+		}
+		i = end
+	}
+}
+
+// WithXMP keeps the XMP packet of JPEG images, returned by RawXMP. Reading a
+// file then goes on past the EXIF segment until the packet is found or the
+// header of the image ends.
+func WithXMP() Option {
+	return func(d *Data) {
+		d.keepXMP = true
+	}
+}
+
+// resetXMP starts a new XMP search, unless the packet is not wanted.
+func (d *Data) resetXMP() {
+	d.xmp = nil
+	d.xmpHead = nil
+	d.xmpScan = 0
+	d.xmpDone = !d.keepXMP
+}
+
+// findXMP looks for the XMP packet in b, a whole image, if it is wanted.
+func (d *Data) findXMP(b []byte) {
+	d.resetXMP()
+	if d.xmpDone {
+		return
+	}
+	if xmp, _ := jpegXMP(b); xmp != nil {
+		d.xmp = append(make([]byte, 0, len(xmp)), xmp...)
+	}
+	d.xmpDone = true
+}
+
+// scanXMP feeds p to the XMP search and reports whether it needs more bytes.
+// The search resumes at the first segment the bytes fed before did not
+// complete.
+func (d *Data) scanXMP(p []byte) bool {
+	if d.xmpDone {
+		return false
+	}
+	d.xmpHead = append(d.xmpHead, p...)
+	xmp, next, more := jpegXMPFrom(d.xmpHead, d.xmpScan)
+	d.xmpScan = next
+	if xmp != nil || !more {
+		if xmp != nil {
+			d.xmp = append(make([]byte, 0, len(xmp)), xmp...)
+		}
+		d.xmpHead = nil
+		d.xmpScan = 0
+		d.xmpDone = true
+	}
+	return !d.xmpDone
+}
+
+// finishXMP ends the XMP search, the packet was not found if it is still on.
+func (d *Data) finishXMP() {
+	if !d.xmpDone {
+		d.xmp = nil
+	}
+	d.xmpHead = nil
+	d.xmpScan = 0
+	d.xmpDone = true
+}
+
+// RawXMP returns a copy of the XMP packet found in the APP1 segments of a
+// JPEG image, next to its EXIF segment. The packet is only kept with WithXMP.
+// Only the bytes the data was read from are searched: with Write, the packet
+// is found only if it was written too.
+func (d *Data) RawXMP() ([]byte, bool) {
+	if d.xmp == nil {
+		return nil, false
+	}
+	return append(make([]byte, 0, len(d.xmp)), d.xmp...), true
+}
+
+// StripExif returns a copy of the given JPEG image with its EXIF segments and
+// JFIF extension thumbnails removed. Pixel data is copied as is, the image is
+// not re-encoded.
+func StripExif(in []byte) ([]byte, error) {
+	segments, scan, err := jpegSegments(in)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(in))
+	out = append(out, in[:2]...)
+	for _, segment := range segments {
+		if segment.isExif() || segment.isJFXX() {
+			continue
+		}
+		out = append(out, in[segment.start:segment.end]...)
+	}
+	return append(out, in[scan:]...), nil
+}
+
+// jpegSegmentBytes encodes a marker segment with the given payload.
+func jpegSegmentBytes(marker byte, payload []byte) ([]byte, error) {
+	length := len(payload) + 2
+	if length > 0xFFFF {
+		return nil, ErrSegmentTooLarge
+	}
+	segment := make([]byte, 4, 2+length)
+	segment[0] = jpegMarkerPrefix
+	segment[1] = marker
+	binary.BigEndian.PutUint16(segment[2:], uint16(length))
+	return append(segment, payload...), nil
+}
+
+// exifSegmentBytes encodes an APP1 segment carrying the given TIFF structure.
+func exifSegmentBytes(tiff []byte) ([]byte, error) {
+	payload := make([]byte, 0, len(exifHeader)+len(tiff))
+	payload = append(payload, exifHeader...)
+	return jpegSegmentBytes(jpegAPP1, append(payload, tiff...))
+}
+
+// SetOrientation returns a copy of the given JPEG image with its Orientation
+// tag set to o. Only the orientation entry of the EXIF segment is rewritten;
+// if the image has no EXIF segment a minimal one is created.
+func SetOrientation(in []byte, o int) ([]byte, error) {
+	if o < OrientationTopLeft || o > OrientationLeftBottom {
+		return nil, ErrInvalidOrientation
+	}
+
+	segments, _, err := jpegSegments(in)
+	if err != nil {
+		return nil, err
+	}
+
+	// A new EXIF segment goes after any APP0 segments, which JFIF requires to
+	// follow SOI immediately.
+	start, end := 2, 2
+	tiff := minimalTIFF(TagOrientation, o)
+	for _, segment := range segments {
+		if segment.isExif() {
+			tiff, err = setTIFFShort(segment.payload[len(exifHeader):], TagOrientation, o)
+			if err != nil {
+				return nil, err
+			}
+			start, end = segment.start, segment.end
+			break
+		}
+		if segment.marker == jpegAPP0 && start == segment.start {
+			start, end = segment.end, segment.end
+		}
+	}
+
+	segment, err := exifSegmentBytes(tiff)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(in)-(end-start)+len(segment))
+	out = append(out, in[:start]...)
+	out = append(out, segment...)
+	return append(out, in[end:]...), nil
+}
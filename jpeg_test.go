@@ -0,0 +1,135 @@
+package exif
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestStripExif(t *testing.T) {
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	in, err := os.ReadFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	out, err := StripExif(in)
+	assert.NoError(t, err)
+	assert.True(t, len(out) < len(in))
+	assert.True(t, bytes.HasPrefix(out, []byte{0xFF, jpegSOI}))
+	assert.True(t, bytes.HasSuffix(in, out[len(out)-1024:]))
+
+	_, err = ReadBytes(out)
+	assert.Equal(t, ErrNoExifData, err)
+}
+
+func TestStripExifNotJPEG(t *testing.T) {
+	_, err := StripExif([]byte("\x89PNG\r\n\x1a\n"))
+	assert.Equal(t, ErrNotJPEG, err)
+
+	_, err = StripExif([]byte{0xFF, jpegSOI, 0xFF, jpegAPP1, 0x10})
+	assert.Equal(t, ErrMalformedJPEG, err)
+}
+
+func orientationOf(t *testing.T, b []byte) int {
+	exif, err := ReadBytes(b)
+	assert.NoError(t, err)
+	orientation, ok := exif.Tags[TagOrientation].(IntegerTag)
+	assert.True(t, ok)
+	return orientation.IntValue()
+}
+
+func TestSetOrientation(t *testing.T) {
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	in, err := os.ReadFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	out, err := SetOrientation(in, OrientationRightTop)
+	assert.NoError(t, err)
+	assert.Equal(t, len(in), len(out))
+	assert.Equal(t, OrientationRightTop, orientationOf(t, out))
+}
+
+func TestSetOrientationAddsEntry(t *testing.T) {
+	// testlocation.jpg has EXIF data but no Orientation tag.
+	in, err := os.ReadFile("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+
+	out, err := SetOrientation(in, OrientationLeftBottom)
+	assert.NoError(t, err)
+	assert.Equal(t, OrientationLeftBottom, orientationOf(t, out))
+
+	exif, err := ReadBytes(out)
+	assert.NoError(t, err)
+	assert.Equal(t, "LGE", exif.Make())
+}
+
+func TestSetOrientationWithoutExif(t *testing.T) {
+	in, err := os.ReadFile("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+	stripped, err := StripExif(in)
+	assert.NoError(t, err)
+
+	out, err := SetOrientation(stripped, OrientationBottomRight)
+	assert.NoError(t, err)
+	assert.Equal(t, OrientationBottomRight, orientationOf(t, out))
+}
+
+func TestSetOrientationInvalid(t *testing.T) {
+	in, err := os.ReadFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	_, err = SetOrientation(in, 0)
+	assert.Equal(t, ErrInvalidOrientation, err)
+	_, err = SetOrientation(in, 9)
+	assert.Equal(t, ErrInvalidOrientation, err)
+}
+
+func TestRawXMP(t *testing.T) {
+	exif := New(WithXMP())
+	err := exif.Open("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+	xmp, ok := exif.RawXMP()
+	assert.True(t, ok)
+	assert.True(t, bytes.Contains(xmp, []byte("x:xmpmeta")))
+
+	b, err := os.ReadFile("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+	exif = New(WithXMP())
+	assert.NoError(t, exif.ParseBytes(b))
+	parsed, ok := exif.RawXMP()
+	assert.True(t, ok)
+	assert.Equal(t, xmp, parsed)
+
+	// Written one byte at a time, the search resumes where it stopped.
+	exif = New(WithXMP())
+	for i := range b {
+		_, err = exif.Write(b[i : i+1])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, exif.Parse())
+	written, ok := exif.RawXMP()
+	assert.True(t, ok)
+	assert.Equal(t, xmp, written)
+
+	segments, _, err := jpegSegments(b)
+	assert.NoError(t, err)
+	cut := segments[1].start + 3
+	_, next, more := jpegXMPFrom(b[:cut], 0)
+	assert.True(t, more)
+	assert.Equal(t, segments[1].start, next)
+	found, _, more := jpegXMPFrom(b, next)
+	assert.False(t, more)
+	assert.Equal(t, xmp, found)
+
+	// The packet is not searched for unless asked.
+	exif, err = Read("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+	_, ok = exif.RawXMP()
+	assert.False(t, ok)
+
+	exif = New(WithXMP())
+	err = exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	_, ok = exif.RawXMP()
+	assert.False(t, ok)
+}
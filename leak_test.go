@@ -0,0 +1,44 @@
+//go:build leak && linux
+// +build leak,linux
+
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// residentBytes returns the resident set size of the current process.
+func residentBytes(t *testing.T) int64 {
+	statm, err := os.ReadFile("/proc/self/statm")
+	assert.NoError(t, err)
+	pages, err := strconv.ParseInt(strings.Fields(string(statm))[1], 10, 64)
+	assert.NoError(t, err)
+	return pages * int64(os.Getpagesize())
+}
+
+// TestReadDoesNotLeak only runs with the leak build tag:
+//
+//	go test -tags leak -run TestReadDoesNotLeak
+func TestReadDoesNotLeak(t *testing.T) {
+	read := func(n int) {
+		for i := 0; i < n; i++ {
+			_, err := Read("_examples/resources/test.jpg")
+			assert.NoError(t, err)
+		}
+		runtime.GC()
+	}
+
+	read(1000)
+	before := residentBytes(t)
+	read(10000)
+	after := residentBytes(t)
+
+	// Leaking the name and value buffers of every entry would grow the
+	// process by tens of megabytes over this many reads.
+	assert.True(t, after-before < 16<<20, "RSS grew from %d to %d bytes", before, after)
+}
@@ -0,0 +1,74 @@
+package exif
+
+/*
+#include <libexif/exif-data.h>
+#include <libexif/exif-loader.h>
+#include <libexif/exif-log.h>
+
+ExifLog* new_exif_log(void);
+*/
+import "C"
+
+import (
+	"sync"
+)
+
+// Levels of libexif log messages.
+const LogDebug = 1
+const LogNoMemory = 2
+const LogCorruptData = 3
+
+var logHandler struct {
+	sync.RWMutex
+	f func(level int, domain, msg string)
+}
+
+// SetLogFunc registers a function that receives libexif's diagnostics while
+// EXIF data is loaded and parsed. The level is one of LogDebug, LogNoMemory
+// or LogCorruptData and the domain names the libexif component that logged
+// the message. Pass nil to stop logging.
+func SetLogFunc(f func(level int, domain, msg string)) {
+	logHandler.Lock()
+	defer logHandler.Unlock()
+	logHandler.f = f
+}
+
+func logging() bool {
+	logHandler.RLock()
+	defer logHandler.RUnlock()
+	return logHandler.f != nil
+}
+
+//export goExifLog
+func goExifLog(code C.int, domain *C.char, msg *C.char) {
+	logHandler.RLock()
+	f := logHandler.f
+	logHandler.RUnlock()
+
+	if f != nil {
+		f(int(code), C.GoString(domain), C.GoString(msg))
+	}
+}
+
+// newLoader creates an exif loader that logs to the registered log function.
+func newLoader() *C.ExifLoader {
+	loader := C.exif_loader_new()
+	if loader != nil && logging() {
+		if log := C.new_exif_log(); log != nil {
+			C.exif_loader_log(loader, log)
+			C.exif_log_unref(log)
+		}
+	}
+	return loader
+}
+
+// attachLog makes exifData log to the registered log function.
+func attachLog(exifData *C.ExifData) {
+	if !logging() {
+		return
+	}
+	if log := C.new_exif_log(); log != nil {
+		C.exif_data_log(exifData, log)
+		C.exif_log_unref(log)
+	}
+}
@@ -0,0 +1,23 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSetLogFunc(t *testing.T) {
+	var messages []string
+	SetLogFunc(func(level int, domain, msg string) {
+		messages = append(messages, domain+": "+msg)
+	})
+	defer SetLogFunc(nil)
+
+	// An EXIF segment with an unknown byte order.
+	b := []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x10}
+	b = append(b, "Exif\x00\x00XX\x00\x00\x00\x00\x00\x00"...)
+	b = append(b, 0xFF, 0xD9)
+
+	err := New().ParseBytes(b)
+	assert.Equal(t, ErrNoExifData, err)
+	assert.True(t, len(messages) > 0)
+}
@@ -0,0 +1,72 @@
+package exif
+
+import (
+	"bytes"
+	"errors"
+)
+
+// jpegStart is the SOI marker followed by the prefix of the next marker.
+var jpegStart = []byte{jpegMarkerPrefix, jpegSOI, jpegMarkerPrefix}
+
+// ReadAll reads the EXIF data of every JPEG image stored in b, such as the
+// frames of an MPO file or the still of a motion photo, in the order they
+// appear, so that the i-th data belongs to the i-th image. Embedded images
+// without EXIF data get empty data; ErrNoExifData is only returned if no image
+// has any. An image whose EXIF data cannot be read does not stop the others
+// from being read: it gets the tags read before the failure, or empty data,
+// and its error is joined into the returned one along with the data of all
+// images. Input that is not a JPEG stream is read like ReadBytes does, as a
+// single image.
+func ReadAll(b []byte) ([]*Data, error) {
+	if !bytes.HasPrefix(b, jpegStart) {
+		data, err := ReadBytes(b)
+		if data == nil {
+			return nil, err
+		}
+		return []*Data{data}, err
+	}
+
+	var all []*Data
+	var errs []error
+	found := false
+	for _, start := range jpegStreams(b) {
+		data, err := ReadBytes(b[start:])
+		if err != nil && err != ErrNoExifData {
+			errs = append(errs, err)
+		}
+		if data == nil {
+			data = New()
+		}
+		all = append(all, data)
+		found = found || err == nil
+	}
+	if len(errs) > 0 {
+		return all, errors.Join(errs...)
+	}
+	if !found {
+		return nil, ErrNoExifData
+	}
+	return all, nil
+}
+
+// jpegStreams returns the offsets of the JPEG streams in b, which must start
+// with one. SOI markers within the header of a stream, like the one of an
+// EXIF thumbnail, do not start a new stream; they cannot appear in
+// entropy-coded data.
+func jpegStreams(b []byte) []int {
+	var starts []int
+	i := 0
+	for {
+		if _, scan, err := jpegSegments(b[i:]); err == nil {
+			starts = append(starts, i)
+			i += scan
+		} else {
+			i++
+		}
+		next := bytes.Index(b[i:], jpegStart)
+		if next < 0 {
+			return starts
+		}
+		i += next
+	}
+}
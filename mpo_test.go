@@ -0,0 +1,63 @@
+package exif
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestReadAll(t *testing.T) {
+	b, err := os.ReadFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+
+	// The EXIF thumbnail is not an image of its own.
+	all, err := ReadAll(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(all))
+	assert.Equal(t, "FUJIFILM", all[0].Make())
+
+	left := buildJPEG(t, buildTIFF([]testEntry{asciiEntry(TagMake, "Left")}, nil, nil))
+	right := buildJPEG(t, buildTIFF([]testEntry{asciiEntry(TagMake, "Right")}, nil, nil))
+	mpo := append(append(b, left...), right...)
+
+	all, err = ReadAll(mpo)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(all))
+	assert.Equal(t, "FUJIFILM", all[0].Make())
+	assert.Equal(t, "Left", all[1].Make())
+	assert.Equal(t, "Right", all[2].Make())
+
+	stripped, err := StripExif(b)
+	assert.NoError(t, err)
+	_, err = ReadAll(stripped)
+	assert.Equal(t, ErrNoExifData, err)
+
+	// A frame without EXIF data keeps its place.
+	all, err = ReadAll(append(append(stripped, left...), right...))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(all))
+	assert.Equal(t, 0, len(all[0].Tags))
+	assert.Equal(t, "Left", all[1].Make())
+	assert.Equal(t, "Right", all[2].Make())
+
+	// A truncated frame keeps its place and the tags read from it, and does
+	// not discard the frames after it.
+	tiff := buildTIFF([]testEntry{shortEntry(TagOrientation, 6), shortEntry(TagSamplesPerPixel, 3)}, nil, nil)
+	truncated := buildJPEG(t, tiff[:8+2+12+6])
+	all, err = ReadAll(append(append(append(stripped, left...), truncated...), right...))
+	assert.True(t, errors.Is(err, ErrTruncatedExif))
+	assert.Equal(t, 4, len(all))
+	assert.Equal(t, 0, len(all[0].Tags))
+	assert.Equal(t, "Left", all[1].Make())
+	assert.Equal(t, 6, all[2].Tags[TagOrientation].(IntegerTag).IntValue())
+	assert.Equal(t, "Right", all[3].Make())
+
+	all, err = ReadAll(append(append([]byte(nil), stripped...), truncated...))
+	assert.True(t, errors.Is(err, ErrTruncatedExif))
+	assert.Equal(t, 2, len(all))
+
+	all, err = ReadAll(buildPNG(buildTIFF([]testEntry{asciiEntry(TagMake, "Maker")}, nil, nil)))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(all))
+}
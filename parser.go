@@ -0,0 +1,56 @@
+package exif
+
+// Parser reads the EXIF data of many files in turn, reusing its tag maps, read
+// buffers and exif loader from one file to the next. Call Reset between files
+// and Close once done.
+type Parser struct {
+	Data
+}
+
+// NewParser creates a Parser configured with the given options.
+func NewParser(opts ...Option) *Parser {
+	p := &Parser{}
+	p.Tags = make(map[int]Tag)
+	p.keepLoader = true
+	for _, opt := range opts {
+		opt(&p.Data)
+	}
+	return p
+}
+
+// ParseFile loads the EXIF data of the given file.
+func (p *Parser) ParseFile(path string) error {
+	return p.Open(path)
+}
+
+// Reset forgets the tags of the last file, keeping the options. The maps are
+// cleared rather than allocated again, and the exif loader used by Write is
+// created again on the next call; the one used by ReadFile is kept.
+func (p *Parser) Reset() {
+	d := &p.Data
+	d.releaseExifData()
+	d.cleanup()
+
+	for key := range d.Tags {
+		delete(d.Tags, key)
+	}
+	for _, tags := range d.ifdTags {
+		for key := range tags {
+			delete(tags, key)
+		}
+	}
+	d.startParse()
+	for key := range d.unhandled {
+		delete(d.unhandled, key)
+	}
+	for key := range d.makerNotes {
+		delete(d.makerNotes, key)
+	}
+	d.hasNotes = false
+	for _, tags := range d.duplicates {
+		for key := range tags {
+			delete(tags, key)
+		}
+	}
+	d.resetXMP()
+}
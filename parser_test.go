@@ -0,0 +1,69 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParser(t *testing.T) {
+	p := NewParser(WithUntrimmedValues())
+	defer p.Close()
+
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	err := p.ParseFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "FUJIFILM", p.Make())
+
+	p.Reset()
+	assert.Equal(t, 0, len(p.Tags))
+	assert.Equal(t, 0, len(p.TagsInIFD(IFD0)))
+	assert.Equal(t, 0, len(p.RawEntries()))
+	assert.Equal(t, "", p.Make())
+	_, ok := p.MakerNotes()
+	assert.False(t, ok)
+	// The loader and buffers of ReadFile are kept for the next file.
+	assert.NotNil(t, p.readLoader)
+	assert.Equal(t, readHeaderSize, len(p.headerBuf))
+
+	err = p.ParseFile("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+	assert.True(t, p.keepSpaces)
+
+	exif, err := Read("_examples/resources/testlocation.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, len(exif.Tags), len(p.Tags))
+	assert.Equal(t, "LGE", p.Make())
+	_, ok = p.GPS()
+	assert.True(t, ok)
+
+	// Other data keep no loader around.
+	assert.Nil(t, exif.readLoader)
+
+	// A loader created before logging was turned on is replaced.
+	SetLogFunc(func(level int, domain, msg string) {})
+	defer SetLogFunc(nil)
+	p.Reset()
+	err = p.ParseFile("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	assert.True(t, p.readLogs)
+}
+
+func BenchmarkRead(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Read("_examples/resources/test.jpg"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParser(b *testing.B) {
+	b.ReportAllocs()
+	p := NewParser()
+	for i := 0; i < b.N; i++ {
+		if err := p.ParseFile("_examples/resources/test.jpg"); err != nil {
+			b.Fatal(err)
+		}
+		p.Reset()
+	}
+}
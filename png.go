@@ -0,0 +1,53 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngExif returns the offset and length of the payload of the eXIf chunk of a
+// PNG image. Only the chunk headers are read.
+func pngExif(src *source) (uint64, uint64, bool) {
+	signature, _ := src.read(0, uint64(len(pngSignature)))
+	if !bytes.Equal(signature, pngSignature) {
+		return 0, 0, false
+	}
+
+	i := uint64(len(pngSignature))
+	for {
+		header, ok := src.read(i, 8)
+		if !ok {
+			return 0, 0, false
+		}
+		length := uint64(binary.BigEndian.Uint32(header))
+		chunkType := string(header[4:8])
+		data := i + 8
+		if data+length > uint64(src.size) {
+			return 0, 0, false
+		}
+		switch chunkType {
+		case "eXIf":
+			return data, length, true
+		case "IEND":
+			return 0, 0, false
+		}
+		// Skip the data and the CRC.
+		i = data + length + 4
+	}
+}
+
+// parsePNG loads the EXIF data of a PNG image, stored in its eXIf chunk.
+func (d *Data) parsePNG(src *source) error {
+	d.startParse()
+	offset, length, ok := pngExif(src)
+	if !ok {
+		return ErrNoExifData
+	}
+	payload, ok := src.read(offset, length)
+	if !ok {
+		return ErrNoExifData
+	}
+	return d.parseTIFF(exifSource(payload))
+}
@@ -0,0 +1,37 @@
+package exif
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"hash/crc32"
+	"testing"
+)
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], chunkType)
+	chunk = append(chunk, data...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	return append(chunk, crc...)
+}
+
+// buildPNG builds a 1x1 PNG header, with an eXIf chunk if tiff is not nil.
+func buildPNG(tiff []byte) []byte {
+	b := append([]byte{}, pngSignature...)
+	b = append(b, pngChunk("IHDR", []byte{0, 0, 0, 1, 0, 0, 0, 1, 8, 0, 0, 0, 0})...)
+	if tiff != nil {
+		b = append(b, pngChunk("eXIf", tiff)...)
+	}
+	return append(b, pngChunk("IEND", nil)...)
+}
+
+func TestReadPNGWithoutExif(t *testing.T) {
+	_, err := ReadBytes(buildPNG(nil))
+	assert.Equal(t, ErrNoExifData, err)
+
+	// A truncated chunk.
+	_, err = ReadBytes(buildPNG(nil)[:20])
+	assert.Equal(t, ErrNoExifData, err)
+}
@@ -0,0 +1,55 @@
+package exif
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// source gives access to parts of an image through an io.ReaderAt, so that
+// only the ranges holding metadata are copied into memory. The first read
+// error is kept, reads past the end are not errors.
+type source struct {
+	r    io.ReaderAt
+	size int64
+	err  error
+}
+
+// bytesSource returns a source reading from b.
+func bytesSource(b []byte) *source {
+	return &source{r: bytes.NewReader(b), size: int64(len(b))}
+}
+
+// exifSource returns the TIFF structure held by an EXIF chunk or item of an
+// image other than JPEG as a source. The formats store the structure alone,
+// but some writers keep the JPEG style header in front of it.
+func exifSource(payload []byte) *source {
+	return bytesSource(bytes.TrimPrefix(payload, exifHeader))
+}
+
+// fileSource returns the part of f that starts at offset as a source, or false
+// if f is not a regular file, such as a pipe, and cannot be read at an offset.
+func fileSource(f *os.File, offset int64) (*source, bool) {
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() || offset < 0 || offset > info.Size() {
+		return nil, false
+	}
+	size := info.Size() - offset
+	return &source{r: io.NewSectionReader(f, offset, size), size: size}, true
+}
+
+// read returns a copy of the n bytes at offset, or false if they do not lie
+// within the source or cannot be read.
+func (s *source) read(offset, n uint64) ([]byte, bool) {
+	if offset > uint64(s.size) || n > uint64(s.size)-offset {
+		return nil, false
+	}
+	b := make([]byte, n)
+	if m, err := s.r.ReadAt(b, int64(offset)); m < len(b) {
+		if s.err == nil {
+			s.err = err
+		}
+		return nil, false
+	}
+	return b, true
+}
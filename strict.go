@@ -0,0 +1,116 @@
+package exif
+
+import "fmt"
+
+// ifd0Formats lists the formats the specification allows for the tags of
+// IFD0 and IFD1.
+var ifd0Formats = map[int][]int{
+	TagImageWidth:                {exifFormatShort, exifFormatLong},
+	TagImageLength:               {exifFormatShort, exifFormatLong},
+	TagCompression:               {exifFormatShort},
+	TagPhotometricInterpretation: {exifFormatShort},
+	TagMake:                      {exifFormatString},
+	TagModel:                     {exifFormatString},
+	TagOrientation:               {exifFormatShort},
+	TagSamplesPerPixel:           {exifFormatShort},
+	TagXResolution:               {exifFormatFloat},
+	TagYResolution:               {exifFormatFloat},
+	TagPlanarConfiguration:       {exifFormatShort},
+	TagResolutionUnit:            {exifFormatShort},
+	TagXPTitle:                   {exifFormatByte},
+	TagXPComment:                 {exifFormatByte},
+	TagXPAuthor:                  {exifFormatByte},
+	TagXPKeywords:                {exifFormatByte},
+	TagXPSubject:                 {exifFormatByte},
+}
+
+// tagFormats lists, by IFD, the formats the specification allows for the tags
+// this package interprets. Tags that are not listed are not checked.
+var tagFormats = [ifdCount]map[int][]int{
+	IFD0: ifd0Formats,
+	IFD1: ifd0Formats,
+	IFDExif: {
+		TagExposureTime:             {exifFormatFloat},
+		TagFNumber:                  {exifFormatFloat},
+		TagExposureProgram:          {exifFormatShort},
+		TagISOSpeedRatings:          {exifFormatShort},
+		TagRecommendedExposureIndex: {exifFormatLong},
+		TagISOSpeed:                 {exifFormatLong},
+		TagDateTimeOriginal:         {exifFormatString},
+		TagShutterSpeedValue:        {exifFormatSRational},
+		TagApertureValue:            {exifFormatFloat},
+		TagMeteringMode:             {exifFormatShort},
+		TagFlash:                    {exifFormatShort},
+		TagFocalLength:              {exifFormatFloat},
+		TagMakerNote:                {exifFormatUndefined},
+		TagSubSecTimeOriginal:       {exifFormatString},
+		TagColorSpace:               {exifFormatShort},
+		TagPixelXDimension:          {exifFormatShort, exifFormatLong},
+		TagPixelYDimension:          {exifFormatShort, exifFormatLong},
+		TagSensingMethod:            {exifFormatShort},
+		TagExposureMode:             {exifFormatShort},
+		TagWhiteBalance:             {exifFormatShort},
+		TagFocalLengthIn35mmFilm:    {exifFormatShort},
+		TagSceneCaptureType:         {exifFormatShort},
+		TagLensMake:                 {exifFormatString},
+		TagLensModel:                {exifFormatString},
+	},
+	IFDGPS: {
+		TagLatitudeRef:        {exifFormatString},
+		TagLatitude:           {exifFormatFloat},
+		TagLongitudeRef:       {exifFormatString},
+		TagLongitude:          {exifFormatFloat},
+		TagAltitudeRef:        {exifFormatByte},
+		TagAltitude:           {exifFormatFloat},
+		TagGPSTimeStamp:       {exifFormatFloat},
+		TagGPSSpeedRef:        {exifFormatString},
+		TagGPSSpeed:           {exifFormatFloat},
+		TagGPSTrackRef:        {exifFormatString},
+		TagGPSTrack:           {exifFormatFloat},
+		TagGPSImgDirectionRef: {exifFormatString},
+		TagGPSImgDirection:    {exifFormatFloat},
+		TagGPSDateStamp:       {exifFormatString},
+	},
+}
+
+// FormatError is returned in strict mode for a tag stored with a format the
+// specification does not allow for it.
+type FormatError struct {
+	Ifd    int
+	Tag    int
+	Format int
+}
+
+func (e *FormatError) Error() string {
+	return fmt.Sprintf(`Tag 0x%04x of IFD %d has invalid format %d.`, e.Tag, e.Ifd, e.Format)
+}
+
+// WithStrict makes parsing fail with a *FormatError when a tag is stored with
+// a format the specification does not allow for it, such as an Orientation
+// that is not a SHORT, and with ErrMalformedExif when a tag has no data. By
+// default such tags are read as well as possible. In strict mode libexif does
+// not fix the data up to the specification either, so tags are checked as
+// they are stored.
+func WithStrict() Option {
+	return func(d *Data) {
+		d.strict = true
+	}
+}
+
+// checkFormat returns the error strict mode reports for a tag of the given IFD
+// stored with format, or nil if the format is valid.
+func checkFormat(ifd, tag, format int) error {
+	if ifd < 0 || ifd >= ifdCount {
+		return nil
+	}
+	formats, ok := tagFormats[ifd][tag]
+	if !ok {
+		return nil
+	}
+	for _, f := range formats {
+		if f == format {
+			return nil
+		}
+	}
+	return &FormatError{Ifd: ifd, Tag: tag, Format: format}
+}
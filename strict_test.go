@@ -0,0 +1,35 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	// http://www.exif.org/samples/fujifilm-mx1700.jpg
+	exif := New(WithStrict())
+	err := exif.Open("_examples/resources/test.jpg")
+	assert.NoError(t, err)
+	assert.Equal(t, "FUJIFILM", exif.Make())
+
+	// An Orientation stored as a LONG.
+	jpeg := buildJPEG(t, buildTIFF([]testEntry{longEntry(TagOrientation, OrientationRightTop)}, nil, nil))
+	err = New(WithStrict()).ParseBytes(jpeg)
+	assert.Equal(t, &FormatError{Ifd: IFD0, Tag: TagOrientation, Format: exifFormatLong}, err)
+	assert.Equal(t, "Tag 0x0112 of IFD 0 has invalid format 4.", err.Error())
+
+	// Lenient mode reads it anyway.
+	exif, err = ReadBytes(jpeg)
+	assert.NoError(t, err)
+	assert.True(t, exif.Has(TagOrientation))
+
+	// A GPS latitude stored as text.
+	jpeg = buildJPEG(t, buildTIFF(nil, nil, []testEntry{asciiEntry(TagLatitude, "40.446")}))
+	err = New(WithStrict()).ParseBytes(jpeg)
+	assert.Equal(t, &FormatError{Ifd: IFDGPS, Tag: TagLatitude, Format: exifFormatString}, err)
+
+	// Tags that are not in the table are not checked.
+	jpeg = buildJPEG(t, buildTIFF(nil, []testEntry{longEntry(0x9000, 230)}, nil))
+	exif = New(WithStrict())
+	assert.NoError(t, exif.ParseBytes(jpeg))
+}
@@ -0,0 +1,62 @@
+package exif
+
+/*
+#include <libexif/exif-tag.h>
+*/
+import "C"
+
+import (
+	"sort"
+)
+
+// TagDescriptor describes a tag known to libexif.
+type TagDescriptor struct {
+	Id          int
+	Name        string
+	Description string
+	// Ifd is the IFD the tag is recorded in. Tags recorded in both IFD0 and
+	// IFD1, like Orientation, are described for IFD0.
+	Ifd int
+}
+
+// KnownTags returns the tags of libexif's tag table, ordered by IFD and id.
+// Tags sharing an id in different IFDs, like GPSLatitudeRef and
+// InteroperabilityIndex, are described separately.
+func KnownTags() []TagDescriptor {
+	var tags []TagDescriptor
+	seen := make(map[TagDescriptor]bool)
+
+	count := int(C.exif_tag_table_count())
+	for n := 0; n < count; n++ {
+		tableName := C.exif_tag_table_get_name(C.uint(n))
+		if tableName == nil {
+			continue
+		}
+		name := C.GoString(tableName)
+		tag := C.exif_tag_table_get_tag(C.uint(n))
+
+		for ifd := IFD0; ifd < ifdCount; ifd++ {
+			ifdName := C.exif_tag_get_name_in_ifd(tag, C.ExifIfd(ifd))
+			if ifdName == nil || C.GoString(ifdName) != name {
+				continue
+			}
+			descriptor := TagDescriptor{Id: int(tag), Name: name, Ifd: ifd}
+			if !seen[descriptor] {
+				seen[descriptor] = true
+				if description := C.exif_tag_get_description_in_ifd(tag, C.ExifIfd(ifd)); description != nil {
+					descriptor.Description = C.GoString(description)
+				}
+				tags = append(tags, descriptor)
+			}
+			break
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Ifd != tags[j].Ifd {
+			return tags[i].Ifd < tags[j].Ifd
+		}
+		return tags[i].Id < tags[j].Id
+	})
+	return tags
+}
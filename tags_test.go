@@ -0,0 +1,31 @@
+package exif
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKnownTags(t *testing.T) {
+	tags := KnownTags()
+	assert.True(t, len(tags) > 100)
+
+	byName := make(map[string]TagDescriptor)
+	for _, tag := range tags {
+		byName[tag.Name] = tag
+	}
+
+	assert.Equal(t, TagOrientation, byName["Orientation"].Id)
+	assert.Equal(t, IFD0, byName["Orientation"].Ifd)
+	assert.True(t, byName["Orientation"].Description != "")
+	assert.Equal(t, IFDExif, byName["FNumber"].Ifd)
+
+	// Both tags with id 1 are described, each in its IFD.
+	assert.Equal(t, TagLatitudeRef, byName["GPSLatitudeRef"].Id)
+	assert.Equal(t, IFDGPS, byName["GPSLatitudeRef"].Ifd)
+	assert.Equal(t, TagLatitudeRef, byName["InteroperabilityIndex"].Id)
+	assert.Equal(t, IFDInteroperability, byName["InteroperabilityIndex"].Ifd)
+
+	for i := 1; i < len(tags); i++ {
+		assert.True(t, tags[i-1].Ifd < tags[i].Ifd || (tags[i-1].Ifd == tags[i].Ifd && tags[i-1].Id <= tags[i].Id))
+	}
+}
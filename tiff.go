@@ -0,0 +1,477 @@
+package exif
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+const tiffEntrySize = 12
+
+// tiffByteOrder returns the byte order declared by a TIFF header.
+func tiffByteOrder(tiff []byte) (binary.ByteOrder, bool) {
+	if len(tiff) < 8 {
+		return nil, false
+	}
+	switch string(tiff[:4]) {
+	case "II\x2a\x00":
+		return binary.LittleEndian, true
+	case "MM\x00\x2a":
+		return binary.BigEndian, true
+	}
+	return nil, false
+}
+
+// tiffHeader returns the first four bytes of a TIFF header in the given byte
+// order.
+func tiffHeader(order binary.ByteOrder) []byte {
+	header := []byte("II\x00\x00")
+	order.PutUint16(header[2:], 0x2a)
+	if header[2] == 0 {
+		copy(header, "MM")
+	}
+	return header
+}
+
+// putShortEntry writes a single-component SHORT IFD entry to e.
+func putShortEntry(order binary.ByteOrder, e []byte, tag int, value int) {
+	order.PutUint16(e, uint16(tag))
+	order.PutUint16(e[2:], exifFormatShort)
+	order.PutUint32(e[4:], 1)
+	order.PutUint32(e[8:], 0)
+	order.PutUint16(e[8:], uint16(value))
+}
+
+// setTIFFShort returns a copy of the given TIFF structure with the tag set to
+// a single SHORT value in IFD0. An existing entry is rewritten in place;
+// otherwise IFD0 is copied to the end of the structure with the new entry
+// added, leaving every other offset untouched.
+func setTIFFShort(tiff []byte, tag int, value int) ([]byte, error) {
+	order, ok := tiffByteOrder(tiff)
+	if !ok {
+		return nil, ErrMalformedExif
+	}
+	ifd0 := int(order.Uint32(tiff[4:]))
+	if ifd0 < 8 || ifd0+2 > len(tiff) {
+		return nil, ErrMalformedExif
+	}
+	count := int(order.Uint16(tiff[ifd0:]))
+	entries := ifd0 + 2
+	next := entries + count*tiffEntrySize
+	if next+4 > len(tiff) {
+		return nil, ErrMalformedExif
+	}
+
+	out := make([]byte, len(tiff), len(tiff)+2+(count+1)*tiffEntrySize+5)
+	copy(out, tiff)
+
+	for i := 0; i < count; i++ {
+		e := out[entries+i*tiffEntrySize:]
+		if int(order.Uint16(e)) == tag {
+			putShortEntry(order, e, tag, value)
+			return out, nil
+		}
+	}
+
+	newEntries := make([][]byte, 0, count+1)
+	for i := 0; i < count; i++ {
+		newEntries = append(newEntries, tiff[entries+i*tiffEntrySize:entries+(i+1)*tiffEntrySize])
+	}
+	entry := make([]byte, tiffEntrySize)
+	putShortEntry(order, entry, tag, value)
+	newEntries = append(newEntries, entry)
+	sort.SliceStable(newEntries, func(i, j int) bool {
+		return order.Uint16(newEntries[i]) < order.Uint16(newEntries[j])
+	})
+
+	// IFDs must start on a word boundary.
+	if len(out)%2 != 0 {
+		out = append(out, 0)
+	}
+	order.PutUint32(out[4:], uint32(len(out)))
+
+	var buf [2]byte
+	order.PutUint16(buf[:], uint16(len(newEntries)))
+	out = append(out, buf[:]...)
+	for _, e := range newEntries {
+		out = append(out, e...)
+	}
+	return append(out, tiff[next:next+4]...), nil
+}
+
+// minimalTIFF returns a big-endian TIFF structure whose IFD0 holds a single
+// SHORT entry.
+func minimalTIFF(tag int, value int) []byte {
+	tiff := make([]byte, 8+2+tiffEntrySize+4)
+	copy(tiff, "MM\x00\x2a")
+	binary.BigEndian.PutUint32(tiff[4:], 8)
+	binary.BigEndian.PutUint16(tiff[8:], 1)
+	putShortEntry(binary.BigEndian, tiff[10:], tag, value)
+	return tiff
+}
+
+// tiffFormatSizes maps TIFF field types to the size of one component.
+var tiffFormatSizes = map[int]int{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// tiffPointerTags maps the tags pointing to sub-IFDs to the IFD they hold.
+var tiffPointerTags = map[int]int{
+	0x8769: IFDExif,
+	0x8825: IFDGPS,
+	0xA005: IFDInteroperability,
+}
+
+// The tags locating the JPEG thumbnail of IFD1.
+const tagJPEGInterchangeFormat = 0x201
+const tagJPEGInterchangeFormatLength = 0x202
+
+type tiffEntry struct {
+	tag    int
+	format int
+	count  uint32
+	value  []byte
+	sub    *tiffIFD
+	// offset is where a value that does not fit in the entry was read from,
+	// and where encode leaves it if pinned is set.
+	offset uint32
+	pinned bool
+}
+
+type tiffIFD struct {
+	entries []tiffEntry
+}
+
+// readTIFFIFD reads the IFD at offset, following pointers to sub-IFDs, and
+// returns it along with the offset of the next IFD. Entries for which keep
+// returns false, entries whose value lies outside of the structure, and values
+// too large to ever fit in EXIF data are skipped. An entry table cut short by
+// the end of the structure is read as far as it goes.
+func readTIFFIFD(src *source, order binary.ByteOrder, offset int, ifd int, keep func(ifd, tag int) bool, seen map[int]bool) (*tiffIFD, int, error) {
+	if offset < 8 || seen[offset] {
+		return nil, 0, ErrMalformedExif
+	}
+	b, ok := src.read(uint64(offset), 2)
+	if !ok {
+		return nil, 0, ErrMalformedExif
+	}
+	seen[offset] = true
+
+	count := int(order.Uint16(b))
+	entries := uint64(offset + 2)
+	table, ok := src.read(entries, uint64(count*tiffEntrySize+4))
+	next := 0
+	if ok {
+		next = int(order.Uint32(table[count*tiffEntrySize:]))
+	} else {
+		count = int((uint64(src.size) - entries) / tiffEntrySize)
+		if table, ok = src.read(entries, uint64(count*tiffEntrySize)); !ok {
+			return nil, 0, ErrMalformedExif
+		}
+	}
+
+	result := &tiffIFD{}
+	for i := 0; i < count; i++ {
+		e := table[i*tiffEntrySize : (i+1)*tiffEntrySize]
+		tag := int(order.Uint16(e))
+		format := int(order.Uint16(e[2:]))
+		components := order.Uint32(e[4:])
+
+		if subIFD, ok := tiffPointerTags[tag]; ok && components == 1 {
+			sub, _, err := readTIFFIFD(src, order, int(order.Uint32(e[8:])), subIFD, keep, seen)
+			if err == nil {
+				result.entries = append(result.entries, tiffEntry{tag: tag, format: exifFormatLong, count: 1, sub: sub})
+			}
+			continue
+		}
+
+		componentSize, ok := tiffFormatSizes[format]
+		if !ok || (keep != nil && !keep(ifd, tag)) {
+			continue
+		}
+		size := uint64(componentSize) * uint64(components)
+		var value []byte
+		var valueOffset uint32
+		if size <= 4 {
+			value = e[8 : 8+size]
+		} else {
+			if size > maxExifSize {
+				continue
+			}
+			valueOffset = order.Uint32(e[8:])
+			if value, ok = src.read(uint64(valueOffset), size); !ok {
+				continue
+			}
+		}
+		result.entries = append(result.entries, tiffEntry{tag: tag, format: format, count: components, value: value, offset: valueOffset})
+	}
+
+	return result, next, nil
+}
+
+// encode encodes the IFD at the given offset, followed by the values that do
+// not fit in their entries and then by its sub-IFDs. Pinned values are left
+// out, their entries point to the offset they were read from.
+func (ifd *tiffIFD) encode(order binary.ByteOrder, offset int) []byte {
+	out := make([]byte, 2+len(ifd.entries)*tiffEntrySize+4)
+	order.PutUint16(out, uint16(len(ifd.entries)))
+	for i, entry := range ifd.entries {
+		e := out[2+i*tiffEntrySize:]
+		order.PutUint16(e, uint16(entry.tag))
+		order.PutUint16(e[2:], uint16(entry.format))
+		order.PutUint32(e[4:], entry.count)
+		if entry.sub != nil {
+			continue
+		}
+		if entry.pinned {
+			order.PutUint32(e[8:], entry.offset)
+			continue
+		}
+		if len(entry.value) <= 4 {
+			copy(e[8:12], entry.value)
+			continue
+		}
+		order.PutUint32(e[8:], uint32(offset+len(out)))
+		out = append(out, entry.value...)
+		if len(out)%2 != 0 {
+			out = append(out, 0)
+		}
+	}
+	for i, entry := range ifd.entries {
+		if entry.sub == nil {
+			continue
+		}
+		order.PutUint32(out[2+i*tiffEntrySize+8:], uint32(offset+len(out)))
+		out = append(out, entry.sub.encode(order, offset+len(out))...)
+	}
+	return out
+}
+
+// dropLargestValue removes the entry with the largest out-of-line value from
+// the given IFDs or their sub-IFDs, and reports whether there was one.
+func dropLargestValue(ifds ...*tiffIFD) bool {
+	var owner *tiffIFD
+	index, largest := -1, 4
+	var visit func(*tiffIFD)
+	visit = func(ifd *tiffIFD) {
+		for i, entry := range ifd.entries {
+			if entry.sub != nil {
+				visit(entry.sub)
+			} else if len(entry.value) > largest {
+				owner, index, largest = ifd, i, len(entry.value)
+			}
+		}
+	}
+	for _, ifd := range ifds {
+		if ifd != nil {
+			visit(ifd)
+		}
+	}
+	if owner == nil {
+		return false
+	}
+	owner.entries = append(owner.entries[:index], owner.entries[index+1:]...)
+	return true
+}
+
+// makerNoteEntry returns the MakerNote entry of the Exif IFD ifd0 points to,
+// or nil if there is none.
+func makerNoteEntry(ifd0 *tiffIFD) *tiffEntry {
+	for _, pointer := range ifd0.entries {
+		if pointer.sub == nil || tiffPointerTags[pointer.tag] != IFDExif {
+			continue
+		}
+		for i := range pointer.sub.entries {
+			if pointer.sub.entries[i].tag == TagMakerNote {
+				return &pointer.sub.entries[i]
+			}
+		}
+	}
+	return nil
+}
+
+// compactTIFF rebuilds the metadata of a TIFF structure, that is IFD0, IFD1
+// and the Exif, GPS and Interoperability IFDs, into a new structure no larger
+// than limit bytes. Image data is not copied, so strip and tile offsets in the
+// result point nowhere and the location of the JPEG thumbnail is left out. If
+// the metadata does not fit, the largest values are dropped until it does.
+//
+// Some maker notes, such as Canon's, locate their values by offsets from the
+// start of the TIFF structure, so the MakerNote value keeps its offset when it
+// fits: it comes first and the IFDs follow it. Otherwise it is moved like any
+// other value, and movedNote is set since its offsets no longer hold.
+func compactTIFF(src *source, keep func(ifd, tag int) bool, limit int) (tiff []byte, movedNote bool, err error) {
+	header, _ := src.read(0, 8)
+	order, ok := tiffByteOrder(header)
+	if !ok {
+		return nil, false, ErrMalformedExif
+	}
+
+	// The thumbnail is not copied, so its location would be stale.
+	keepEntry := func(ifd, tag int) bool {
+		if tag == tagJPEGInterchangeFormat || tag == tagJPEGInterchangeFormatLength {
+			return false
+		}
+		return keep == nil || keep(ifd, tag)
+	}
+
+	seen := make(map[int]bool)
+	ifd0, next, err := readTIFFIFD(src, order, int(order.Uint32(header[4:])), IFD0, keepEntry, seen)
+	if err != nil {
+		return nil, false, err
+	}
+	var ifd1 *tiffIFD
+	if next != 0 {
+		ifd1, _, _ = readTIFFIFD(src, order, next, IFD1, keepEntry, seen)
+	}
+
+	if note := makerNoteEntry(ifd0); note != nil && len(note.value) > 4 {
+		note.pinned = note.offset >= 8 && int(note.offset)+len(note.value) <= limit
+		movedNote = !note.pinned
+	}
+
+	for {
+		out := make([]byte, 8, limit)
+		copy(out, header[:4])
+		if note := makerNoteEntry(ifd0); note != nil && note.pinned {
+			out = append(out, make([]byte, int(note.offset)-len(out))...)
+			out = append(out, note.value...)
+			if len(out)%2 != 0 {
+				out = append(out, 0)
+			}
+		}
+		start := len(out)
+		order.PutUint32(out[4:], uint32(start))
+		out = append(out, ifd0.encode(order, start)...)
+		if ifd1 != nil {
+			// The next IFD offset follows the entries of IFD0.
+			order.PutUint32(out[start+2+len(ifd0.entries)*tiffEntrySize:], uint32(len(out)))
+			out = append(out, ifd1.encode(order, len(out))...)
+		}
+		if len(out) <= limit {
+			return out, movedNote, nil
+		}
+		if note := makerNoteEntry(ifd0); note != nil && note.pinned {
+			// Moving the maker note saves the gap in front of it.
+			note.pinned = false
+			movedNote = true
+			continue
+		}
+		if !dropLargestValue(ifd0, ifd1) {
+			return nil, false, ErrMalformedExif
+		}
+	}
+}
+
+// tiffDuplicates returns the entries of the tags that appear more than once
+// in the same IFD, in the order they are stored, by IFD and tag.
+func tiffDuplicates(tiff []byte) [ifdCount]map[int][]tiffEntry {
+	var duplicates [ifdCount]map[int][]tiffEntry
+	order, ok := tiffByteOrder(tiff)
+	if !ok {
+		return duplicates
+	}
+
+	var visit func(ifd *tiffIFD, n int)
+	visit = func(ifd *tiffIFD, n int) {
+		occurrences := make(map[int][]tiffEntry)
+		for _, entry := range ifd.entries {
+			if entry.sub != nil {
+				visit(entry.sub, tiffPointerTags[entry.tag])
+				continue
+			}
+			occurrences[entry.tag] = append(occurrences[entry.tag], entry)
+		}
+		for tag, entries := range occurrences {
+			if len(entries) < 2 {
+				continue
+			}
+			if duplicates[n] == nil {
+				duplicates[n] = make(map[int][]tiffEntry)
+			}
+			duplicates[n][tag] = entries
+		}
+	}
+
+	src := bytesSource(tiff)
+	seen := make(map[int]bool)
+	ifd0, next, err := readTIFFIFD(src, order, int(order.Uint32(tiff[4:])), IFD0, nil, seen)
+	if err != nil {
+		return duplicates
+	}
+	visit(ifd0, IFD0)
+	if next != 0 {
+		if ifd1, _, err := readTIFFIFD(src, order, next, IFD1, nil, seen); err == nil {
+			visit(ifd1, IFD1)
+		}
+	}
+	return duplicates
+}
+
+// singleEntryTIFF returns a TIFF structure holding nothing but entry, in the
+// given IFD.
+func singleEntryTIFF(order binary.ByteOrder, ifd int, entry tiffEntry) []byte {
+	holder := &tiffIFD{entries: []tiffEntry{entry}}
+	pointer := func(tag int, sub *tiffIFD) *tiffIFD {
+		return &tiffIFD{entries: []tiffEntry{{tag: tag, format: exifFormatLong, count: 1, sub: sub}}}
+	}
+
+	ifd0 := holder
+	switch ifd {
+	case IFD1:
+		ifd0 = &tiffIFD{}
+	case IFDExif:
+		ifd0 = pointer(0x8769, holder)
+	case IFDGPS:
+		ifd0 = pointer(0x8825, holder)
+	case IFDInteroperability:
+		ifd0 = pointer(0x8769, pointer(0xA005, holder))
+	}
+
+	out := make([]byte, 8)
+	copy(out, tiffHeader(order))
+	order.PutUint32(out[4:], 8)
+	out = append(out, ifd0.encode(order, len(out))...)
+	if ifd == IFD1 {
+		// The next IFD offset follows the empty entries of IFD0.
+		order.PutUint32(out[8+2:], uint32(len(out)))
+		out = append(out, holder.encode(order, len(out))...)
+	}
+	return out
+}
+
+// tiffTruncated reports whether a TIFF structure is cut short, as by a partial
+// download: its header or the entry table of IFD0 runs past the end of the
+// data. Values and other IFDs pointing past the end are damaged rather than
+// truncated; libexif skips them and reads the rest.
+func tiffTruncated(src *source) bool {
+	header, ok := src.read(0, 8)
+	if !ok {
+		return true
+	}
+	order, ok := tiffByteOrder(header)
+	if !ok {
+		return false
+	}
+	offset := uint64(order.Uint32(header[4:]))
+	if offset < 8 {
+		return false
+	}
+	b, ok := src.read(offset, 2)
+	if !ok {
+		return true
+	}
+	count := uint64(order.Uint16(b))
+	return offset+2+count*tiffEntrySize > uint64(src.size)
+}
+
+// tiffHasIFD0 reports whether a TIFF structure holds an IFD0 with at least one
+// entry, reading nothing else.
+func tiffHasIFD0(src *source) bool {
+	header, _ := src.read(0, 8)
+	order, ok := tiffByteOrder(header)
+	if !ok {
+		return false
+	}
+	b, ok := src.read(uint64(order.Uint32(header[4:])), 2)
+	return ok && order.Uint16(b) > 0
+}
@@ -0,0 +1,253 @@
+package exif
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTIFF(t *testing.T) {
+	tiff := buildTIFF(
+		[]testEntry{asciiEntry(TagMake, "Maker"), asciiEntry(TagModel, "Model"), shortEntry(TagOrientation, OrientationRightTop)},
+		[]testEntry{rationalEntry(TagFNumber, exifFormatFloat, 28, 10)},
+		nil,
+	)
+
+	exif, err := ReadBytes(tiff)
+	assert.NoError(t, err)
+	assert.Equal(t, "Maker", exif.Make())
+	assert.Equal(t, "Model", exif.Model())
+
+	fNumber, ok := exif.FNumber()
+	assert.True(t, ok)
+	assert.Equal(t, 2.8, fNumber)
+}
+
+// buildDNG lays out a TIFF structure the way raw files often do, with the
+// metadata far past the 64KB libexif reads and a large XMP packet in IFD0.
+func buildDNG() []byte {
+	const ifd0Offset = 100000
+	const exifOffset = 200000
+
+	ifd0 := encodeIFD([]testEntry{
+		asciiEntry(TagMake, "Maker"),
+		asciiEntry(TagModel, "Raw Model"),
+		shortEntry(TagOrientation, OrientationRightTop),
+		longEntry(0x8769, exifOffset),
+		{0xC612, exifFormatByte, 4, []byte{1, 4, 0, 0}},
+		{0x02BC, exifFormatByte, 70000, make([]byte, 70000)},
+	}, ifd0Offset)
+	exifIFD := encodeIFD([]testEntry{
+		rationalEntry(TagFNumber, exifFormatFloat, 56, 10),
+	}, exifOffset)
+
+	tiff := make([]byte, exifOffset)
+	copy(tiff, "MM\x00\x2a")
+	binary.BigEndian.PutUint32(tiff[4:], ifd0Offset)
+	copy(tiff[ifd0Offset:], ifd0)
+	return append(tiff, exifIFD...)
+}
+
+func TestReadDNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.dng")
+	err := os.WriteFile(path, buildDNG(), 0644)
+	assert.NoError(t, err)
+
+	exif, err := Read(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "Raw Model", exif.Model())
+	assert.Equal(t, OrientationRightTop, exif.Tags[TagOrientation].(IntegerTag).IntValue())
+
+	fNumber, ok := exif.FNumber()
+	assert.True(t, ok)
+	assert.Equal(t, 5.6, fNumber)
+
+	exif = New()
+	err = exif.OpenContext(context.Background(), path)
+	assert.NoError(t, err)
+	assert.Equal(t, "Raw Model", exif.Model())
+}
+
+func TestReadTIFFFixtures(t *testing.T) {
+	for _, test := range []struct {
+		file         string
+		model        string
+		orientation  int
+		fNumber      float64
+		exposureTime string
+		iso          int
+		taken        time.Time
+	}{
+		// A 4x4 RGB image in a single uncompressed strip.
+		{"testdata/photo.tif", "TIFF Model", OrientationRightTop, 2.8, "1/125 s", 200, time.Date(2024, 5, 1, 12, 30, 45, 0, time.UTC)},
+		// A big endian DNG with an RGB preview in IFD0 and the CFA data in a
+		// SubIFD.
+		{"testdata/photo.dng", "Raw Model", OrientationLeftBottom, 5.6, "1/60 s", 400, time.Date(2024, 6, 2, 8, 15, 0, 0, time.UTC)},
+	} {
+		b, err := os.ReadFile(test.file)
+		assert.NoError(t, err)
+		fromBytes, err := ReadBytes(b)
+		assert.NoError(t, err)
+		exif, err := Read(test.file)
+		assert.NoError(t, err)
+
+		for _, exif := range []*Data{fromBytes, exif} {
+			assert.Equal(t, "Maker", exif.Make(), test.file)
+			assert.Equal(t, test.model, exif.Model(), test.file)
+			assert.Equal(t, test.orientation, exif.Tags[TagOrientation].(IntegerTag).IntValue(), test.file)
+
+			fNumber, ok := exif.FNumber()
+			assert.True(t, ok, test.file)
+			assert.Equal(t, test.fNumber, fNumber, test.file)
+			exposureTime, ok := exif.ExposureTime()
+			assert.True(t, ok, test.file)
+			assert.Equal(t, test.exposureTime, exposureTime, test.file)
+			iso, ok := exif.ISO()
+			assert.True(t, ok, test.file)
+			assert.Equal(t, test.iso, iso, test.file)
+			taken, ok := exif.DateTimeOriginal()
+			assert.True(t, ok, test.file)
+			assert.Equal(t, test.taken, taken, test.file)
+		}
+
+		ok, err := HasExif(test.file)
+		assert.NoError(t, err)
+		assert.True(t, ok, test.file)
+	}
+}
+
+func TestReadFileDNG(t *testing.T) {
+	// A DNG that starts past the offset the file is left at.
+	f, err := os.Create(filepath.Join(t.TempDir(), "embedded"))
+	assert.NoError(t, err)
+	defer f.Close()
+	_, err = f.Write(append([]byte("junk"), buildDNG()...))
+	assert.NoError(t, err)
+	_, err = f.Seek(4, io.SeekStart)
+	assert.NoError(t, err)
+
+	exif := New()
+	err = exif.ReadFile(f)
+	assert.NoError(t, err)
+	assert.Equal(t, "Raw Model", exif.Model())
+
+	// A pipe cannot be read at an offset, so it is read whole.
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	go func() {
+		w.Write(buildDNG())
+		w.Close()
+	}()
+
+	exif = New()
+	err = exif.ReadFile(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "Raw Model", exif.Model())
+}
+
+// buildCanonTIFF encodes a TIFF structure with a Canon maker note, which
+// locates its values by offsets from the start of the TIFF structure.
+func buildCanonTIFF() []byte {
+	imageType := "IMG:EOS JPEG\x00"
+	placeholder := bytes.Repeat([]byte{0xAA}, 2+12+4+len(imageType))
+	tiff := buildTIFF(
+		[]testEntry{asciiEntry(TagMake, "Canon")},
+		[]testEntry{{TagMakerNote, exifFormatUndefined, len(placeholder), placeholder}},
+		nil,
+	)
+
+	offset := bytes.Index(tiff, placeholder)
+	note := tiff[offset : offset+len(placeholder)]
+	binary.BigEndian.PutUint16(note, 1)
+	binary.BigEndian.PutUint16(note[2:], 0x0006)
+	binary.BigEndian.PutUint16(note[4:], exifFormatString)
+	binary.BigEndian.PutUint32(note[6:], uint32(len(imageType)))
+	binary.BigEndian.PutUint32(note[10:], uint32(offset+2+12+4))
+	binary.BigEndian.PutUint32(note[14:], 0)
+	copy(note[18:], imageType)
+	return tiff
+}
+
+func TestCanonMakerNotes(t *testing.T) {
+	tiff := buildCanonTIFF()
+
+	exif, err := ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	notes, ok := exif.MakerNotes()
+	assert.True(t, ok)
+	assert.Equal(t, "IMG:EOS JPEG", notes["ImageType"])
+
+	// The compact copy of the structure keeps the maker note at its offset.
+	exif, err = ReadBytes(tiff)
+	assert.NoError(t, err)
+	fromTIFF, ok := exif.MakerNotes()
+	assert.True(t, ok)
+	assert.Equal(t, notes, fromTIFF)
+}
+
+func TestCompactTIFF(t *testing.T) {
+	tiff, movedNote, err := compactTIFF(bytesSource(buildDNG()), nil, maxExifSize-len(exifHeader))
+	assert.NoError(t, err)
+	assert.True(t, len(tiff) < 1024)
+	assert.False(t, movedNote)
+
+	canon := buildCanonTIFF()
+	tiff, movedNote, err = compactTIFF(bytesSource(canon), nil, maxExifSize-len(exifHeader))
+	assert.NoError(t, err)
+	assert.False(t, movedNote)
+	offset := bytes.Index(canon, []byte{0, 1, 0, 6})
+	assert.Equal(t, canon[offset:offset+32], tiff[offset:offset+32])
+
+	// There is no room to keep the maker note at its offset.
+	_, movedNote, err = compactTIFF(bytesSource(canon), nil, offset+16)
+	assert.NoError(t, err)
+	assert.True(t, movedNote)
+
+	_, _, err = compactTIFF(bytesSource([]byte("II\x2a\x00\xff\xff\xff\xff")), nil, maxExifSize)
+	assert.Equal(t, ErrMalformedExif, err)
+
+	_, _, err = compactTIFF(bytesSource([]byte("not a tiff")), nil, maxExifSize)
+	assert.Equal(t, ErrMalformedExif, err)
+}
+
+func TestParseWithByteOrder(t *testing.T) {
+	tiff := buildTIFF([]testEntry{shortEntry(TagOrientation, OrientationRightTop)}, nil, nil)
+
+	damaged := append([]byte("XX\x00\x00"), tiff[4:]...)
+	assert.Equal(t, ErrNoExifData, New().ParseBytes(damaged))
+
+	exif := New()
+	err := exif.ParseWithByteOrder(damaged, binary.BigEndian)
+	assert.NoError(t, err)
+	assert.Equal(t, OrientationRightTop, exif.Tags[TagOrientation].(IntegerTag).IntValue())
+
+	// The forced byte order wins over the declared one.
+	exif = New()
+	err = exif.ParseWithByteOrder(buildJPEG(t, tiff), binary.LittleEndian)
+	assert.NoError(t, err)
+	assert.Equal(t, OrientationRightTop<<8, exif.Tags[TagOrientation].(IntegerTag).IntValue())
+
+	// PNG and WebP images are read like ParseBytes does, their signature is
+	// not taken for a damaged TIFF header.
+	for _, b := range [][]byte{buildPNG(tiff), buildWebP(tiff)} {
+		exif = New()
+		err = exif.ParseWithByteOrder(b, binary.BigEndian)
+		assert.NoError(t, err)
+		assert.Equal(t, OrientationRightTop, exif.Tags[TagOrientation].(IntegerTag).IntValue())
+
+		exif = New()
+		err = exif.ParseWithByteOrder(b, binary.LittleEndian)
+		assert.NoError(t, err)
+		assert.Equal(t, OrientationRightTop<<8, exif.Tags[TagOrientation].(IntegerTag).IntValue())
+	}
+
+	assert.Equal(t, []byte("II\x2a\x00"), tiffHeader(binary.LittleEndian))
+	assert.Equal(t, []byte("MM\x00\x2a"), tiffHeader(binary.BigEndian))
+}
@@ -0,0 +1,50 @@
+package exif
+
+import "encoding/binary"
+
+// webpExif returns the offset and length of the payload of the EXIF chunk of a
+// WebP image. Only the chunk headers are read.
+func webpExif(src *source) (uint64, uint64, bool) {
+	riff, _ := src.read(0, 12)
+	if !isWebP(riff) {
+		return 0, 0, false
+	}
+
+	i := uint64(12)
+	for {
+		header, ok := src.read(i, 8)
+		if !ok {
+			return 0, 0, false
+		}
+		fourCC := string(header[:4])
+		length := uint64(binary.LittleEndian.Uint32(header[4:]))
+		data := i + 8
+		if data+length > uint64(src.size) {
+			return 0, 0, false
+		}
+		if fourCC == "EXIF" {
+			return data, length, true
+		}
+		// Chunks are padded to an even size.
+		i = data + length + length&1
+	}
+}
+
+// isWebP reports whether b starts with a RIFF header of the WEBP form.
+func isWebP(b []byte) bool {
+	return len(b) >= 12 && string(b[0:4]) == "RIFF" && string(b[8:12]) == "WEBP"
+}
+
+// parseWebP loads the EXIF data of a WebP image, stored in its EXIF chunk.
+func (d *Data) parseWebP(src *source) error {
+	d.startParse()
+	offset, length, ok := webpExif(src)
+	if !ok {
+		return ErrNoExifData
+	}
+	payload, ok := src.read(offset, length)
+	if !ok {
+		return ErrNoExifData
+	}
+	return d.parseTIFF(exifSource(payload))
+}
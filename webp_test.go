@@ -0,0 +1,70 @@
+package exif
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func webpChunk(fourCC string, data []byte) []byte {
+	chunk := make([]byte, 8, 9+len(data))
+	copy(chunk, fourCC)
+	binary.LittleEndian.PutUint32(chunk[4:], uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// buildWebP builds an extended (VP8X) WebP container, with an EXIF chunk if
+// tiff is not nil. The image data is not valid but is never decoded.
+func buildWebP(tiff []byte) []byte {
+	vp8x := make([]byte, 10)
+	body := []byte("WEBP")
+	if tiff != nil {
+		vp8x[0] = 0x08
+	}
+	body = append(body, webpChunk("VP8X", vp8x)...)
+	// An odd sized chunk to exercise the padding.
+	body = append(body, webpChunk("VP8 ", []byte{0, 0, 0})...)
+	if tiff != nil {
+		body = append(body, webpChunk("EXIF", tiff)...)
+	}
+
+	b := []byte("RIFF\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(body)))
+	return append(b, body...)
+}
+
+func TestReadWebPFixture(t *testing.T) {
+	// A 1x1 lossless image with a GPS IFD.
+	b, err := os.ReadFile("testdata/geotagged.webp")
+	assert.NoError(t, err)
+	fromBytes, err := ReadBytes(b)
+	assert.NoError(t, err)
+	exif, err := Read("testdata/geotagged.webp")
+	assert.NoError(t, err)
+
+	for _, exif := range []*Data{fromBytes, exif} {
+		assert.Equal(t, "Phone", exif.Model())
+
+		info, ok := exif.GPS()
+		assert.True(t, ok)
+		assert.InDelta(t, 59+19.0/60+45.72/3600, info.Latitude, 1e-9)
+		assert.InDelta(t, 18+4.0/60+10.92/3600, info.Longitude, 1e-9)
+		assert.True(t, info.HasAltitude)
+		assert.Equal(t, 28.0, info.Altitude)
+
+		dateTime, ok := exif.GPSDateTime()
+		assert.True(t, ok)
+		assert.Equal(t, time.Date(2023, 7, 14, 10, 2, 3, 0, time.UTC), dateTime)
+	}
+}
+
+func TestReadWebPWithoutExif(t *testing.T) {
+	_, err := ReadBytes(buildWebP(nil))
+	assert.Equal(t, ErrNoExifData, err)
+}
@@ -0,0 +1,47 @@
+package exif
+
+import (
+	"strings"
+	"unicode/utf16"
+)
+
+// XPTitle returns the title set by Windows Explorer.
+func (d *Data) XPTitle() (string, bool) {
+	return d.xpString(TagXPTitle)
+}
+
+// XPComment returns the comment set by Windows Explorer.
+func (d *Data) XPComment() (string, bool) {
+	return d.xpString(TagXPComment)
+}
+
+// XPAuthor returns the author set by Windows Explorer.
+func (d *Data) XPAuthor() (string, bool) {
+	return d.xpString(TagXPAuthor)
+}
+
+// XPKeywords returns the keywords set by Windows Explorer, separated by
+// semicolons.
+func (d *Data) XPKeywords() (string, bool) {
+	return d.xpString(TagXPKeywords)
+}
+
+// XPSubject returns the subject set by Windows Explorer.
+func (d *Data) XPSubject() (string, bool) {
+	return d.xpString(TagXPSubject)
+}
+
+// xpString decodes one of the Windows XP* tags of IFD0, stored as a BYTE
+// array holding a null terminated UTF-16LE string.
+func (d *Data) xpString(tag int) (string, bool) {
+	t, ok := d.ifdTags[IFD0][tag].(IntegerTag)
+	if !ok {
+		return "", false
+	}
+	values := t.IntValues()
+	units := make([]uint16, len(values)/2)
+	for i := range units {
+		units[i] = uint16(values[2*i]) | uint16(values[2*i+1])<<8
+	}
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00"), true
+}
@@ -0,0 +1,56 @@
+package exif
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"unicode/utf16"
+)
+
+func xpEntry(tag int, value string) testEntry {
+	units := utf16.Encode([]rune(value + "\x00"))
+	data := make([]byte, 2*len(units))
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(data[2*i:], u)
+	}
+	return testEntry{tag, exifFormatByte, len(data), data}
+}
+
+func TestXPTags(t *testing.T) {
+	tiff := buildTIFF([]testEntry{
+		xpEntry(TagXPTitle, "Sunset över sjön"),
+		xpEntry(TagXPKeywords, "lake;🌅"),
+	}, nil, nil)
+	exif, err := ReadBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+
+	title, ok := exif.XPTitle()
+	assert.True(t, ok)
+	assert.Equal(t, "Sunset över sjön", title)
+
+	keywords, ok := exif.XPKeywords()
+	assert.True(t, ok)
+	assert.Equal(t, "lake;🌅", keywords)
+
+	_, ok = exif.XPComment()
+	assert.False(t, ok)
+
+	// The occurrence kept by the duplicate policy is decoded.
+	tiff = buildTIFF([]testEntry{
+		xpEntry(TagXPTitle, "First"),
+		xpEntry(TagXPTitle, "Last"),
+	}, nil, nil)
+	exif = New(WithDuplicatePolicy(DuplicateLastWins))
+	err = exif.ParseBytes(buildJPEG(t, tiff))
+	assert.NoError(t, err)
+	title, ok = exif.XPTitle()
+	assert.True(t, ok)
+	assert.Equal(t, "Last", title)
+
+	// The title of the data parsed last is decoded.
+	err = exif.ParseBytes(buildJPEG(t, buildTIFF([]testEntry{xpEntry(TagXPTitle, "Other")}, nil, nil)))
+	assert.NoError(t, err)
+	title, ok = exif.XPTitle()
+	assert.True(t, ok)
+	assert.Equal(t, "Other", title)
+}